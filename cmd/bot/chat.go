@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/SevereCloud/vksdk/v2/api"
+	"github.com/SevereCloud/vksdk/v2/events"
+	"github.com/SevereCloud/vksdk/v2/object"
+	log "github.com/sirupsen/logrus"
+)
+
+// chatPeerOffset is added to chat id in peer id
+const chatPeerOffset = 2e9
+
+const chatKicked = "kicked"
+
+// isChat return true if peer is a conversation
+func isChat(peerID int) bool {
+	return peerID > chatPeerOffset
+}
+
+// botMentioned return true if text mentions the community
+func botMentioned(ctx context.Context, text string) bool {
+	club := "club" + strconv.Itoa(events.GroupIDFromContext(ctx))
+
+	return strings.Contains(text, "["+club+"|") || strings.Contains(text, "@"+club)
+}
+
+// checkChatAdmin return true if user is admin of chat. Otherwise it return
+// message for user.
+func (s *Service) checkChatAdmin(peerID, userID int) (string, bool) {
+	members, err := s.vk.MessagesGetConversationMembers(api.Params{
+		"peer_id": peerID,
+	})
+	if err != nil {
+		if errors.Is(err, api.ErrMessagesChatNotAdmin) {
+			return "Сделайте бота администратором беседы, чтобы управлять настройками", false
+		}
+
+		log.WithError(err).WithField("peer_id", peerID).Error("VK API messages.getConversationMembers")
+
+		return "Не удалось проверить права, попробуйте позже", false
+	}
+
+	for _, m := range members.Items {
+		if m.MemberID == userID && (m.IsOwner || m.IsAdmin) {
+			return "", true
+		}
+	}
+
+	return "Настройки беседы доступны только администраторам", false
+}
+
+// chatMember return true if user is member of chat
+func (s *Service) chatMember(peerID, userID int) (bool, error) {
+	members, err := s.vk.MessagesGetConversationMembers(api.Params{
+		"peer_id": peerID,
+	})
+	if err != nil {
+		log.WithError(err).WithField("peer_id", peerID).Error("VK API messages.getConversationMembers")
+		return false, err
+	}
+
+	for _, m := range members.Items {
+		if m.MemberID == userID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// sendChatSecret send URL and token of chat webhook to admin in private
+// messages. It return status for chat.
+func (s *Service) sendChatSecret(peerID, userID int, hook string) (string, error) {
+	message, err := s.settingMessage(peerID, hook, "Настройки для Webhooks беседы\n\n", true)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.vk.MessagesSend(api.Params{
+		"peer_id":          userID,
+		"random_id":        0,
+		"message":          message,
+		"dont_parse_links": true,
+	})
+
+	switch {
+	case err == nil:
+		return "URL и Secret Token отправлены в личные сообщения", nil
+	case errors.Is(err, api.ErrMessagesDenySend):
+		return "Разрешите сообщения от сообщества, чтобы получить URL и Secret Token", nil
+	default:
+		log.WithError(err).WithField("user_id", userID).Error("Message send error")
+		return "Не удалось отправить URL и Secret Token, попробуйте позже", nil
+	}
+}
+
+// chatAction handle service messages of chat about the bot
+func (s *Service) chatAction(ctx context.Context, peerID int, action object.MessagesMessageAction) {
+	if action.MemberID != -events.GroupIDFromContext(ctx) {
+		return
+	}
+
+	switch action.Type {
+	case object.ChatInviteUser:
+		log.WithField("peer_id", peerID).Info("Bot invited to chat")
+
+		if err := s.setKey(peerID, chatDisabledKey, ""); err != nil {
+			return
+		}
+
+		s.reply(
+			peerID,
+			"Привет! Администраторы беседы могут получить настройки webhook кнопкой ниже",
+			s.KeyboardBuild(peerID),
+		)
+	case object.ChatKickUser:
+		s.disableChat(peerID)
+	}
+}
+
+// disableChat disable webhook of chat, when bot can't write to it
+func (s *Service) disableChat(peerID int) {
+	log.WithField("peer_id", peerID).Info("Bot kicked from chat, webhook disabled")
+
+	_ = s.setKey(peerID, chatDisabledKey, chatKicked)
+}
+
+// chatDisabled return true if webhook of chat is disabled
+func (s *Service) chatDisabled(peerID int) (bool, error) {
+	value, err := s.getKey(peerID, chatDisabledKey)
+
+	return value != "", err
+}
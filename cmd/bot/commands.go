@@ -0,0 +1,462 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SevereCloud/gitlabvk/pkg/gitlab"
+	"github.com/SevereCloud/vksdk/v2/object"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// muteOff is argument of mute command, which unmutes notifications
+	muteOff = "off"
+	maxMute = 30 * day
+
+	// emojiOff and emojiFor are arguments of emoji command
+	emojiOff = "off"
+	emojiFor = "for"
+	maxEmoji = 10
+)
+
+// errBadDuration returned for malformed mute duration
+var errBadDuration = errors.New("bad duration")
+
+// emojiNameRe match name of GitLab emoji
+var emojiNameRe = regexp.MustCompile(`^[a-z0-9_+\-]{1,64}$`)
+
+// mentionRe match mention of community at the beginning of message
+var mentionRe = regexp.MustCompile(`^\s*(\[club\d+\|[^\]]*\]|@club\d+)[,\s]*`)
+
+// textCommands return payload commands of text commands and their aliases
+func textCommands() map[string]string {
+	return map[string]string{
+		"settings":    getSetting,
+		"настройки":   getSetting,
+		"reset":       resetToken,
+		"сброс":       resetToken,
+		"subscribe":   subscribeEvents,
+		"подписаться": subscribeEvents,
+		"unsubscribe": unsubscribeEvents,
+		"отписаться":  unsubscribeEvents,
+		"filters":     showFilters,
+		"фильтры":     showFilters,
+		"mute":        muteNotifications,
+		"тишина":      muteNotifications,
+		"unmute":      muteNotifications,
+		"звук":        muteNotifications,
+		"status":      showStatus,
+		"статус":      showStatus,
+		"help":        showHelp,
+		"помощь":      showHelp,
+		"start":       showHelp,
+		"refs":        refFilters,
+		"ветки":       refFilters,
+		"filter":      issueFilters,
+		"фильтр":      issueFilters,
+		"hooks":       hookList,
+		"вебхуки":     hookList,
+		"link":        linkAccount,
+		"привязать":   linkAccount,
+		"unlink":      unlinkAccount,
+		"отвязать":    unlinkAccount,
+		"mentions":    toggleMentions,
+		"упоминания":  toggleMentions,
+		"emoji":       setEmoji,
+		"эмодзи":      setEmoji,
+	}
+}
+
+// textCommand convert text command like "/refs main" to button payload.
+// Argument like "#id" before other arguments selects named webhook. Unknown
+// command is converted to help.
+func textCommand(text string) (ButtonPayload, bool) {
+	text = strings.TrimSpace(mentionRe.ReplaceAllString(text, ""))
+	if !strings.HasPrefix(text, "/") {
+		return ButtonPayload{}, false
+	}
+
+	name, args := splitCommand(text[1:])
+	name = strings.ToLower(name)
+
+	var hook string
+	if strings.HasPrefix(args, "#") {
+		hook, args = splitCommand(args[1:])
+	}
+
+	switch name {
+	case "hook", "вебхук":
+		return hookTextCommand(args)
+	case "unmute", "звук":
+		args = muteOff
+	}
+
+	command, ok := textCommands()[name]
+	if !ok {
+		return ButtonPayload{Command: showHelp, Payload: name}, false
+	}
+
+	if command == showHelp {
+		args = ""
+	}
+
+	return ButtonPayload{Command: command, Payload: args, Hook: hook}, true
+}
+
+// hookTextCommand convert arguments of /hook command to button payload
+func hookTextCommand(args string) (ButtonPayload, bool) {
+	sub, args := splitCommand(args)
+
+	switch strings.ToLower(sub) {
+	case "new":
+		return ButtonPayload{Command: createHook, Payload: args}, true
+	case "rename":
+		hook, name := splitCommand(args)
+		return ButtonPayload{Command: renameHook, Hook: strings.TrimPrefix(hook, "#"), Payload: name}, true
+	case "delete":
+		hook, _ := splitCommand(args)
+		return ButtonPayload{Command: deleteHook, Hook: strings.TrimPrefix(hook, "#")}, true
+	default:
+		return ButtonPayload{Command: hookList}, true
+	}
+}
+
+// splitCommand split text to first word and the rest
+func splitCommand(text string) (string, string) {
+	if i := strings.IndexAny(text, " \n"); i >= 0 {
+		return text[:i], strings.TrimSpace(text[i:])
+	}
+
+	return text, ""
+}
+
+// adminCommand return true if command changes settings of chat and is
+// allowed only to chat admins
+func adminCommand(command string) bool {
+	switch command {
+	case notSupportedButton, linkAccount, unlinkAccount, showHelp, showStatus:
+		return false
+	default:
+		return true
+	}
+}
+
+// helpMessage return list of text commands. Unknown command is reported
+// first.
+func helpMessage(unknown string) string {
+	text := ""
+	if unknown != "" {
+		text = "Неизвестная команда /" + unknown + "\n\n"
+	}
+
+	return text + "Команды:\n" +
+		"/settings (/настройки) — настройки webhook\n" +
+		"/reset (/сброс) — сбросить ключ доступа\n" +
+		"/subscribe push mr (/подписаться) — включить события\n" +
+		"/unsubscribe job (/отписаться) — выключить события\n" +
+		"/filters (/фильтры) — фильтры веток и issue\n" +
+		"/refs (/ветки) — изменить фильтр веток\n" +
+		"/filter (/фильтр) — изменить фильтр issue и merge request\n" +
+		"/mute 2h (/тишина) — выключить уведомления на время, /unmute (/звук) — включить\n" +
+		"/status (/статус) — состояние уведомлений\n" +
+		"/hooks (/вебхуки) — список webhook\n" +
+		"/emoji thumbsup rocket (/эмодзи) — лента эмодзи, /emoji for mr issue — для чего\n" +
+		"/link username (/привязать) — привязать аккаунт GitLab\n" +
+		"/help (/помощь) — эта справка\n\n" +
+		"События: " + eventNames() + ", all\n" +
+		"Для именованного webhook укажите его id: /subscribe #id push"
+}
+
+// eventNames return names of events for subscribe command
+func eventNames() string {
+	names := make([]string, 0, len(eventKinds()))
+	for _, kind := range eventKinds() {
+		names = append(names, kind.Name)
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// subscribeCommand turn on or off event types listed in payload
+func (s *Service) subscribeCommand(peerID int, p ButtonPayload, fields log.Fields) (
+	message string,
+	keyboard *object.MessagesKeyboard,
+	err error,
+) {
+	keyboard = s.eventKeyboardBuild(peerID, p.Hook)
+
+	if p.Payload == "" {
+		return "Укажите события: /" + p.Command + " push mr\nСобытия: " + eventNames() + ", all", keyboard, nil
+	}
+
+	types, err := eventKindsByName(strings.Fields(p.Payload))
+	if errors.Is(err, errBadPattern) {
+		return "Неизвестное событие: " + err.Error() + "\nСобытия: " + eventNames() + ", all", keyboard, nil
+	}
+
+	log.WithFields(fields).WithFields(log.Fields{
+		"hook":   p.Hook,
+		"events": types,
+	}).Info("User " + p.Command)
+
+	if err = s.setEventsEnabled(peerID, p.Hook, types, p.Command == subscribeEvents); err != nil {
+		return "", nil, err
+	}
+
+	message, err = s.eventsMessageBuild(peerID, p.Hook)
+
+	return message, s.eventKeyboardBuild(peerID, p.Hook), err
+}
+
+// parseMuteDuration parse duration like 30m, 2h or 1d. Russian units ч, м
+// and д are accepted too.
+func parseMuteDuration(text string) (time.Duration, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+
+	var (
+		d   time.Duration
+		err error
+	)
+
+	switch {
+	case strings.HasSuffix(text, "d"), strings.HasSuffix(text, "д"):
+		var days int
+
+		days, err = strconv.Atoi(strings.TrimRight(text, "dд"))
+		d = time.Duration(days) * day
+	default:
+		d, err = time.ParseDuration(strings.NewReplacer("ч", "h", "м", "m").Replace(text))
+	}
+
+	if err != nil || d < time.Minute || d > maxMute {
+		return 0, errBadDuration
+	}
+
+	return d, nil
+}
+
+// muteCommand mute notifications of peer for duration from payload
+func (s *Service) muteCommand(peerID int, arg string, fields log.Fields) (string, error) {
+	if arg == muteOff {
+		log.WithFields(fields).Info("User unmute")
+
+		if err := s.setMutedUntil(peerID, time.Time{}); err != nil {
+			return "", err
+		}
+
+		return "Уведомления включены", nil
+	}
+
+	d, err := parseMuteDuration(arg)
+	if err != nil {
+		return "Укажите время от 1m до 30d: /mute 2h, /mute 30m, /mute 1d\nВключить уведомления: /unmute", nil
+	}
+
+	log.WithFields(fields).WithField("duration", d).Info("User mute")
+
+	if err := s.setMutedUntil(peerID, time.Now().Add(d)); err != nil {
+		return "", err
+	}
+
+	return "Уведомления выключены на " + humanDuration(d) + "\nВключить: /unmute", nil
+}
+
+// statusMessageBuild return state of notifications of peer
+func (s *Service) statusMessageBuild(peerID int) (string, error) {
+	text := "Статус\n\n"
+
+	if isChat(peerID) {
+		disabled, err := s.chatDisabled(peerID)
+		if err != nil {
+			return "", err
+		}
+
+		if disabled {
+			text += "Webhook беседы: отключен, добавьте бота в беседу снова\n"
+		} else {
+			text += "Webhook беседы: работает\n"
+		}
+	}
+
+	until, err := s.mutedUntil(peerID)
+	if err != nil {
+		return "", err
+	}
+
+	if until.IsZero() {
+		text += "Уведомления: включены\n"
+	} else {
+		text += "Уведомления: выключены еще " + humanDuration(time.Until(until)) + "\n"
+	}
+
+	hooks, err := s.hooks(peerID)
+	if err != nil {
+		return "", err
+	}
+
+	text += "Webhooks: " + strconv.Itoa(len(hooks)+1) + "\n"
+
+	events, err := s.eventsMessageBuild(peerID, "")
+	if err != nil {
+		return "", err
+	}
+
+	return text + events, nil
+}
+
+// awardableKind is object, which can be awarded with emoji
+type awardableKind struct {
+	Type string
+	Name string
+}
+
+// awardableKinds return objects of emoji command
+func awardableKinds() []awardableKind {
+	return []awardableKind{
+		{gitlab.AwardableTypeMergeRequest, "mr"},
+		{gitlab.AwardableTypeIssue, "issue"},
+		{gitlab.AwardableTypeNote, "comment"},
+		{gitlab.AwardableTypeSnippet, "snippet"},
+		{gitlab.AwardableTypeCommit, "commit"},
+	}
+}
+
+// awardableTypesByName return awardable types by names like mr. Error
+// contains unknown name.
+func awardableTypesByName(names []string) ([]string, error) {
+	var types []string
+
+	for _, name := range names {
+		found := false
+
+		for _, kind := range awardableKinds() {
+			if strings.EqualFold(name, kind.Name) || strings.EqualFold(name, kind.Type) {
+				types = append(types, kind.Type)
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("%w %q", errBadPattern, name)
+		}
+	}
+
+	return types, nil
+}
+
+// parseEmojiNames return emoji names like thumbsup from text like
+// ":thumbsup: rocket"
+func parseEmojiNames(text string) ([]string, error) {
+	var names []string
+
+	for _, name := range strings.Fields(strings.ToLower(text)) {
+		name = strings.Trim(name, ":,")
+		if !emojiNameRe.MatchString(name) {
+			return nil, fmt.Errorf("%w %q", errBadPattern, name)
+		}
+
+		if !containsFold(names, name) {
+			names = append(names, name)
+		}
+	}
+
+	if len(names) > maxEmoji {
+		return nil, fmt.Errorf("%w: more than %d emoji", errBadPattern, maxEmoji)
+	}
+
+	return names, nil
+}
+
+// emojiMessageBuild return emoji feed settings of peer
+func (s *Service) emojiMessageBuild(peerID int) (string, error) {
+	emoji, err := s.emojiList(peerID)
+	if err != nil {
+		return "", err
+	}
+
+	types, err := s.emojiTypes(peerID)
+	if err != nil {
+		return "", err
+	}
+
+	text := "Эмодзи: выключены\n"
+	if len(emoji) > 0 {
+		text = "Эмодзи: " + strings.Join(emoji, ", ") + "\n"
+	}
+
+	var names []string
+
+	for _, kind := range awardableKinds() {
+		for _, t := range types {
+			if t == kind.Type {
+				names = append(names, kind.Name)
+			}
+		}
+	}
+
+	text += "Для: " + strings.Join(names, ", ") + "\n\n" +
+		"Изменить: /emoji thumbsup rocket\n" +
+		"Для чего: /emoji for mr issue comment snippet commit\n" +
+		"Выключить: /emoji off"
+
+	return text, nil
+}
+
+// emojiCommand set emoji feed of peer. Argument is list of emoji names,
+// "for" with list of awardable objects or "off".
+func (s *Service) emojiCommand(peerID int, arg string, fields log.Fields) (string, error) {
+	name, rest := splitCommand(strings.TrimSpace(arg))
+
+	var (
+		message string
+		err     error
+	)
+
+	switch strings.ToLower(name) {
+	case "":
+	case emojiOff:
+		log.WithFields(fields).Info("User disable emoji")
+
+		err = s.setEmojiList(peerID, nil)
+	case emojiFor:
+		var types []string
+
+		if types, err = awardableTypesByName(strings.Fields(rest)); err == nil && len(types) == 0 {
+			err = fmt.Errorf("%w %q", errBadPattern, rest)
+		}
+
+		if err == nil {
+			log.WithFields(fields).WithField("types", types).Info("User set emoji types")
+
+			err = s.setEmojiTypes(peerID, types)
+		}
+	default:
+		var names []string
+
+		if names, err = parseEmojiNames(arg); err == nil {
+			log.WithFields(fields).WithField("emoji", names).Info("User set emoji")
+
+			err = s.setEmojiList(peerID, names)
+		}
+	}
+
+	if errors.Is(err, errBadPattern) {
+		message = "Неверный аргумент: " + err.Error() + "\n\n"
+		err = nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	text, err := s.emojiMessageBuild(peerID)
+
+	return message + text, err
+}
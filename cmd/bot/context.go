@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"time"
 
 	"github.com/SevereCloud/gitlabvk/pkg/gitlab"
 )
@@ -10,7 +11,8 @@ type contextKey int
 
 const (
 	contextUserID contextKey = iota
-	contextEventType
+	contextHookID
+	contextSalt
 )
 
 // getUserID return userID
@@ -24,13 +26,57 @@ func getUserID(ctx context.Context) int {
 	return 0
 }
 
-// getEventType return gitlab event type
-func getEventType(ctx context.Context) gitlab.EventType {
+// getHookID return id of named webhook. Default webhook has empty id.
+func getHookID(ctx context.Context) string {
 	if ctx != nil {
-		if hc, ok := ctx.Value(contextEventType).(gitlab.EventType); ok {
-			return hc
+		if hook, ok := ctx.Value(contextHookID).(string); ok {
+			return hook
+		}
+	}
+
+	return ""
+}
+
+// getSalt return salt of webhook, which is read once per request
+func getSalt(ctx context.Context) string {
+	if ctx != nil {
+		if salt, ok := ctx.Value(contextSalt).(string); ok {
+			return salt
 		}
 	}
 
 	return ""
 }
+
+// getEventType return gitlab event type
+func getEventType(ctx context.Context) gitlab.EventType {
+	r, _ := gitlab.RequestFromContext(ctx)
+
+	return r.Event
+}
+
+// detachedContext keeps values of parent context, but it is never canceled
+type detachedContext struct {
+	parent context.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) {
+	return
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func (c detachedContext) Value(key interface{}) interface{} {
+	return c.parent.Value(key)
+}
+
+// detach return context with values of ctx, which is not canceled with ctx
+func detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
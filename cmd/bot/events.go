@@ -2,7 +2,6 @@ package main
 
 import (
 	"context"
-	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -16,11 +15,13 @@ import (
 	"github.com/SevereCloud/gitlabvk/pkg/gitlab"
 )
 
-const maxAttemptSendMessage = 3
+const maxInlineRows = 6
 
+// baseRef return branch or tag name of ref like refs/heads/main. Short refs
+// of job and pipeline events are returned as is.
 func baseRef(ref string) string {
 	a := strings.Split(ref, "/")
-	if len(a) > 2 {
+	if len(a) > 2 && a[0] == "refs" {
 		return strings.Join(a[2:], "/")
 	}
 
@@ -74,58 +75,154 @@ func (s *Service) onTagPush(ctx context.Context, e gitlab.EventTagPush) {
 	s.sendMessage(userID, message, keyboard)
 }
 
+func (s *Service) onRelease(ctx context.Context, e gitlab.EventRelease) {
+	var message string
+
+	switch e.Action {
+	case gitlab.ReleaseActionCreate:
+		message = fmt.Sprintf("🚀 new release %s %s (%s)", e.Project.Name, e.Name, e.Tag)
+	case gitlab.ReleaseActionDelete:
+		message = fmt.Sprintf("🗑 remove release %s %s (%s)", e.Project.Name, e.Name, e.Tag)
+	default:
+		message = fmt.Sprintf("🚀 %s release %s %s (%s)", e.Action, e.Project.Name, e.Name, e.Tag)
+	}
+
+	if description := markdownToText(e.Description); description != "" {
+		message += "\n\n" + trimText(description, maxDescriptionLength)
+	}
+
+	keyboard := object.NewMessagesKeyboardInline()
+	rows := 0
+
+	// VK rejects whole message if label is empty or too long
+	addLink := func(link, label string) {
+		label = buttonLabel(label)
+		if link == "" || label == "" || rows >= maxInlineRows {
+			return
+		}
+
+		keyboard.AddRow()
+		keyboard.AddOpenLinkButton(link, label, "")
+		rows++
+	}
+
+	if e.Action != gitlab.ReleaseActionDelete {
+		addLink(e.URL, "Open release")
+	}
+
+	for _, m := range e.Milestones {
+		link := m.WebURL
+		if link == "" && m.IID != 0 {
+			link = fmt.Sprintf("%s/-/milestones/%d", e.Project.WebURL, m.IID)
+		}
+
+		addLink(link, "Milestone "+m.Title)
+	}
+
+	for _, asset := range e.Assets.Links {
+		addLink(asset.URL, asset.Name)
+	}
+
+	userID := getUserID(ctx)
+	s.sendMessage(userID, message, keyboard)
+}
+
 func (s *Service) onIssue(ctx context.Context, e gitlab.EventIssue) {
+	userID := getUserID(ctx)
+	s.sendIssue(userID, "🐛", "issue", e)
+}
+
+func (s *Service) onConfidentialIssue(ctx context.Context, e gitlab.EventIssue) {
+	userID := getUserID(ctx)
+	if enabled, err := s.confidentialEnabled(userID); err != nil || !enabled {
+		log.WithField("userID", userID).Debug("confidential issue skipped")
+		return
+	}
+
+	s.sendIssue(userID, "🔒", "confidential issue", e)
+}
+
+func (s *Service) sendIssue(userID int, icon, kind string, e gitlab.EventIssue) {
 	message := fmt.Sprintf(
-		"🐛 %s %s issue: %s#%d\n%s\n\n%s",
+		"%s %s %s %s: %s#%d\n%s\n\n%s",
+		icon,
 		e.User.Name,
 		e.ObjectAttributes.Action,
+		kind,
 		e.Project.Name, e.ObjectAttributes.IID,
 		e.ObjectAttributes.Title,
 		e.ObjectAttributes.Description,
 	)
 
+	if due := e.ObjectAttributes.DueDate; !due.IsZero() {
+		message += fmt.Sprintf("\n\n📅 due %s (%s)", due, relativeDate(due.Time))
+	}
+
 	link := e.ObjectAttributes.URL
 	keyboard := object.NewMessagesKeyboardInline()
 	keyboard.AddRow()
 	keyboard.AddOpenLinkButton(link, "Open issue", "")
 
-	userID := getUserID(ctx)
-	s.sendMessage(userID, message, keyboard)
+	accounts := make([]account, 0, len(e.Assignees)+1)
+	for _, a := range e.Assignees {
+		accounts = append(accounts, account{Username: a.Username})
+	}
+
+	accounts = append(accounts, account{Username: e.Assignee.Username})
+
+	s.sendMentionMessage(userID, message, keyboard, e.User.Username, accounts)
 }
 
 func (s *Service) onNote(ctx context.Context, e gitlab.EventNote) {
-	message := "💬 " + e.User.Name + " "
+	userID := getUserID(ctx)
+	s.sendNote(userID, "💬", "comment", e)
+}
+
+func (s *Service) onConfidentialNote(ctx context.Context, e gitlab.EventNote) {
+	userID := getUserID(ctx)
+	if enabled, err := s.confidentialEnabled(userID); err != nil || !enabled {
+		log.WithField("userID", userID).Debug("confidential note skipped")
+		return
+	}
+
+	s.sendNote(userID, "🔒", "confidential comment", e)
+}
+
+func (s *Service) sendNote(userID int, icon, kind string, e gitlab.EventNote) {
+	message := icon + " " + e.User.Name + " "
 
 	switch e.ObjectAttributes.NoteableType {
 	case gitlab.NoteableTypeIssue:
 		message += fmt.Sprintf(
-			"write comment to issue %s#%d\n\n%s",
-			e.Project.Name, e.Issue.IID,
+			"write %s to issue %s#%d\n\n%s",
+			kind, e.Project.Name, e.Issue.IID,
 			e.ObjectAttributes.Note,
 		)
 	case gitlab.NoteableTypeCommit:
 		message += fmt.Sprintf(
-			"write comment to commit %s#%s\n\n%s",
-			e.Project.Name, e.Commit.ID[:8],
+			"write %s to commit %s#%s\n\n%s",
+			kind, e.Project.Name, e.Commit.ID[:8],
 			e.ObjectAttributes.Note,
 		)
 	case gitlab.NoteableTypeMergeRequest:
 		message += fmt.Sprintf(
-			"write comment to MR %s#%d\n\n%s",
-			e.Project.Name, e.MergeRequest.IID,
+			"write %s to MR %s#%d\n\n%s",
+			kind, e.Project.Name, e.MergeRequest.IID,
 			e.ObjectAttributes.Note,
 		)
 	case gitlab.NoteableTypeSnippet:
 		message += fmt.Sprintf(
-			"write comment to snippet %s $%d\n\n%s",
-			e.Project.Name, e.Snippet.ID,
+			"write %s to snippet %s $%d\n\n%s",
+			kind, e.Project.Name, e.Snippet.ID,
 			e.ObjectAttributes.Note,
 		)
 	default:
 		log.WithField("noteable", e.ObjectAttributes.NoteableType).Warn("Not found noteable type")
 		message = fmt.Sprintf(
-			"💬 %s write comment to %s\n\n%s",
+			"%s %s write %s to %s\n\n%s",
+			icon,
 			e.User.Name,
+			kind,
 			e.Project.Name,
 			e.ObjectAttributes.Note,
 		)
@@ -136,8 +233,7 @@ func (s *Service) onNote(ctx context.Context, e gitlab.EventNote) {
 	keyboard.AddRow()
 	keyboard.AddOpenLinkButton(link, "Open comment", "")
 
-	userID := getUserID(ctx)
-	s.sendMessage(userID, message, keyboard)
+	s.sendMentionMessage(userID, message, keyboard, e.User.Username, noteAccounts(e.ObjectAttributes.Note))
 }
 
 func (s *Service) onMergeRequest(ctx context.Context, e gitlab.EventMergeRequest) {
@@ -155,8 +251,14 @@ func (s *Service) onMergeRequest(ctx context.Context, e gitlab.EventMergeRequest
 	keyboard.AddRow()
 	keyboard.AddOpenLinkButton(link, "Open", "")
 
+	accounts := assigneeAccounts(
+		e.Assignees,
+		e.Reviewers,
+		[]gitlab.MergeAssignee{e.Assignee, e.ObjectAttributes.Assignee},
+	)
+
 	userID := getUserID(ctx)
-	s.sendMessage(userID, message, keyboard)
+	s.sendMentionMessage(userID, message, keyboard, e.User.Username, accounts)
 }
 
 func (s *Service) onJob(ctx context.Context, e gitlab.EventJob) {
@@ -179,12 +281,18 @@ func (s *Service) onJob(ctx context.Context, e gitlab.EventJob) {
 	}
 
 	message += fmt.Sprintf(
-		" %s %s %s\n",
+		" %s %s %s",
 		e.BuildStage,
 		e.BuildName,
 		e.BuildStatus,
 	)
 
+	if !e.BuildStartedAt.IsZero() && !e.BuildFinishedAt.IsZero() {
+		message += " in " + humanDuration(e.BuildFinishedAt.Sub(e.BuildStartedAt.Time))
+	}
+
+	message += "\n"
+
 	// link := fmt.Sprintf("%s/pipelines/%d", e.Repository.Homepage, e.PipelineID)
 
 	// keyboard := object.NewMessagesKeyboardInline()
@@ -192,14 +300,12 @@ func (s *Service) onJob(ctx context.Context, e gitlab.EventJob) {
 	// keyboard.AddOpenLinkButton(link, "Open pipeline", "")
 
 	userID := getUserID(ctx)
-	if s.getKey(userID, pipelineLastID) == strconv.Itoa(e.PipelineID) {
+	if s.isLastChain(userID, pipelineLastID, e.PipelineID) {
 		log.Debug("job in pipe")
 		s.sendPipelineMessage(userID, message, nil)
 	} else {
 		log.Debug("job new")
-		s.setKey(userID, pipelineLastID, strconv.Itoa(e.PipelineID))
-		id := s.sendMessage(userID, message, nil)
-		s.setKey(userID, pipelineMessageID, strconv.Itoa(id))
+		s.sendNewChainMessage(userID, pipelineLastID, e.PipelineID, pipelineMessageID, message, nil)
 	}
 }
 
@@ -222,11 +328,17 @@ func (s *Service) onPipeline(ctx context.Context, e gitlab.EventPipeline) {
 	}
 
 	message += fmt.Sprintf(
-		" pipeline #%d %s\n",
+		" pipeline #%d %s",
 		e.ObjectAttributes.ID,
 		e.ObjectAttributes.Status,
 	)
 
+	if e.ObjectAttributes.Duration > 0 {
+		message += " in " + humanDuration(time.Duration(e.ObjectAttributes.Duration)*time.Second)
+	}
+
+	message += "\n"
+
 	userID := getUserID(ctx)
 
 	link := fmt.Sprintf("%s/pipelines/%d", e.Project.WebURL, e.ObjectAttributes.ID)
@@ -235,17 +347,256 @@ func (s *Service) onPipeline(ctx context.Context, e gitlab.EventPipeline) {
 	keyboard.AddRow()
 	keyboard.AddOpenLinkButton(link, "Open pipeline", "")
 
-	if s.getKey(userID, pipelineLastID) == strconv.Itoa(e.ObjectAttributes.ID) {
+	if s.isLastChain(userID, pipelineLastID, e.ObjectAttributes.ID) {
 		if e.ObjectAttributes.Status == gitlab.StatusFailed {
 			s.sendMessage(userID, message, keyboard)
 		} else {
 			s.sendPipelineMessage(userID, message, nil)
 		}
 	} else {
-		s.setKey(userID, pipelineLastID, strconv.Itoa(e.ObjectAttributes.ID))
-		id := s.sendMessage(userID, message, nil)
-		s.setKey(userID, pipelineMessageID, strconv.Itoa(id))
+		s.sendNewChainMessage(userID, pipelineLastID, e.ObjectAttributes.ID, pipelineMessageID, message, nil)
+	}
+}
+
+func (s *Service) onDeployment(ctx context.Context, e gitlab.EventDeployment) {
+	var status string
+
+	switch e.Status {
+	case gitlab.StatusCreated:
+		status = "🆕"
+	case gitlab.StatusRunning:
+		status = "🚀"
+	case gitlab.StatusCanceled:
+		status = "🚫"
+	case gitlab.StatusFailed:
+		status = "🗙"
+	case gitlab.StatusSuccess:
+		status = "✅"
+	default:
+		status = "💼"
+	}
+
+	status += fmt.Sprintf(" deployment #%d %s\n", e.DeploymentID, e.Status)
+
+	keyboard := object.NewMessagesKeyboardInline()
+
+	if e.EnvironmentExternalURL != "" {
+		keyboard.AddRow()
+		keyboard.AddOpenLinkButton(e.EnvironmentExternalURL, "Open "+e.Environment, "")
+	}
+
+	if e.CommitURL != "" {
+		keyboard.AddRow()
+		keyboard.AddOpenLinkButton(e.CommitURL, "Commit "+e.ShortSHA, "")
+	}
+
+	if e.DeployableURL != "" {
+		keyboard.AddRow()
+		keyboard.AddOpenLinkButton(e.DeployableURL, "Open job", "")
+	}
+
+	userID := getUserID(ctx)
+
+	if s.isLastChain(userID, deploymentLastID, e.DeploymentID) {
+		s.sendChainMessage(userID, deploymentMessageID, status, keyboard)
+	} else {
+		message := fmt.Sprintf(
+			"📦 %s deploy %s to %s\n%s\n\n%s",
+			e.User.Name,
+			e.Project.Name,
+			e.Environment,
+			e.CommitTitle,
+			status,
+		)
+
+		s.sendNewChainMessage(userID, deploymentLastID, e.DeploymentID, deploymentMessageID, message, keyboard)
+	}
+}
+
+func (s *Service) onMember(ctx context.Context, e gitlab.EventMember) {
+	var message string
+
+	switch e.EventName {
+	case gitlab.SystemHookUserAddToGroup:
+		message = fmt.Sprintf("➕ %s (@%s) got %s access to group %s",
+			e.UserName, e.UserUsername, e.GroupAccess, e.GroupPath)
+	case gitlab.SystemHookUserUpdateForGroup:
+		message = fmt.Sprintf("✏️ %s (@%s) now has %s access to group %s",
+			e.UserName, e.UserUsername, e.GroupAccess, e.GroupPath)
+	case gitlab.SystemHookUserRemoveFromGroup:
+		message = fmt.Sprintf("➖ %s (@%s) lost %s access to group %s",
+			e.UserName, e.UserUsername, e.GroupAccess, e.GroupPath)
+	case gitlab.SystemHookUserAccessRequest:
+		message = fmt.Sprintf("🙋 %s (@%s) requested access to group %s",
+			e.UserName, e.UserUsername, e.GroupPath)
+	case gitlab.SystemHookUserAccessDenied:
+		message = fmt.Sprintf("🚫 %s (@%s) denied access to group %s",
+			e.UserName, e.UserUsername, e.GroupPath)
+	default:
+		message = fmt.Sprintf("👥 %s %s (@%s) group %s",
+			e.EventName, e.UserName, e.UserUsername, e.GroupPath)
+	}
+
+	if !e.ExpiresAt.IsZero() {
+		message += "\naccess expires " + relativeTime(e.ExpiresAt.Time)
+	}
+
+	userID := getUserID(ctx)
+	s.sendMessage(userID, message, nil)
+}
+
+func (s *Service) onSubgroup(ctx context.Context, e gitlab.EventSubgroup) {
+	var message string
+
+	switch e.EventName {
+	case gitlab.SystemHookSubgroupCreate:
+		message = fmt.Sprintf("👥 new subgroup %s in %s", e.FullPath, e.ParentFullPath)
+	case gitlab.SystemHookSubgroupDestroy:
+		message = fmt.Sprintf("🗑 subgroup %s removed from %s", e.FullPath, e.ParentFullPath)
+	default:
+		message = fmt.Sprintf("👥 %s subgroup %s", e.EventName, e.FullPath)
+	}
+
+	userID := getUserID(ctx)
+	s.sendMessage(userID, message, nil)
+}
+
+func (s *Service) onProject(ctx context.Context, e gitlab.EventProject) {
+	var message string
+
+	switch e.EventName {
+	case gitlab.SystemHookProjectCreate:
+		message = fmt.Sprintf("📁 new project %s", e.PathWithNamespace)
+	case gitlab.SystemHookProjectDestroy:
+		message = fmt.Sprintf("🗑 project %s removed", e.PathWithNamespace)
+	default:
+		message = fmt.Sprintf("📁 %s project %s", e.EventName, e.PathWithNamespace)
 	}
+
+	userID := getUserID(ctx)
+	s.sendMessage(userID, message, nil)
+}
+
+// emojiSymbol return symbol of popular emoji name
+func emojiSymbol(name string) string {
+	switch name {
+	case "thumbsup":
+		return "👍"
+	case "thumbsdown":
+		return "👎"
+	case "white_check_mark":
+		return "✅"
+	case "heavy_check_mark":
+		return "✔️"
+	case "rocket":
+		return "🚀"
+	case "tada":
+		return "🎉"
+	case "heart":
+		return "❤️"
+	case "eyes":
+		return "👀"
+	case "fire":
+		return "🔥"
+	}
+
+	return ":" + name + ":"
+}
+
+func (s *Service) onEmoji(ctx context.Context, e gitlab.EventEmoji) {
+	if e.EventType != gitlab.EmojiEventTypeAward {
+		return
+	}
+
+	userID := getUserID(ctx)
+	name := e.ObjectAttributes.Name
+
+	selected := false
+
+	emoji, err := s.emojiList(userID)
+	if err != nil {
+		return
+	}
+
+	for _, v := range emoji {
+		if v == name {
+			selected = true
+			break
+		}
+	}
+
+	types, err := s.emojiTypes(userID)
+	if err != nil {
+		return
+	}
+
+	if !selected || !containsFold(types, e.ObjectAttributes.AwardableType) {
+		log.WithFields(log.Fields{
+			"userID":    userID,
+			"emoji":     name,
+			"awardable": e.ObjectAttributes.AwardableType,
+		}).Debug("emoji skipped")
+
+		return
+	}
+
+	message := emojiSymbol(name) + " " + e.User.Name + " "
+
+	var link string
+
+	switch e.ObjectAttributes.AwardableType {
+	case gitlab.AwardableTypeMergeRequest:
+		message += fmt.Sprintf("MR %s#%d\n%s", e.Project.Name, e.MergeRequest.IID, e.MergeRequest.Title)
+		link = e.MergeRequest.URL
+	case gitlab.AwardableTypeIssue:
+		message += fmt.Sprintf("issue %s#%d\n%s", e.Project.Name, e.Issue.IID, e.Issue.Title)
+		link = e.Issue.URL
+	case gitlab.AwardableTypeNote:
+		message += fmt.Sprintf("comment in %s\n\n%s", e.Project.Name, trimText(e.Note.Note, maxDescriptionLength))
+		link = e.Note.URL
+	case gitlab.AwardableTypeSnippet:
+		message += fmt.Sprintf("snippet %s $%d\n%s", e.Project.Name, e.Snippet.ID, e.Snippet.Title)
+		link = e.Snippet.URL
+	default:
+		message += fmt.Sprintf("%s in %s", e.ObjectAttributes.AwardableType, e.Project.Name)
+	}
+
+	var keyboard *object.MessagesKeyboard
+
+	if link != "" {
+		keyboard = object.NewMessagesKeyboardInline()
+		keyboard.AddRow()
+		keyboard.AddOpenLinkButton(link, "Open", "")
+	}
+
+	s.sendMessage(userID, message, keyboard)
+}
+
+func (s *Service) onFeatureFlag(ctx context.Context, e gitlab.EventFeatureFlag) {
+	state := "🔴 inactive"
+	if e.ObjectAttributes.Active {
+		state = "🟢 active"
+	}
+
+	message := fmt.Sprintf(
+		"🚩 %s toggled feature flag %s in %s\nnow %s",
+		e.User.Name,
+		e.ObjectAttributes.Name,
+		e.Project.Name,
+		state,
+	)
+
+	if e.ObjectAttributes.Description != "" {
+		message += "\n\n" + e.ObjectAttributes.Description
+	}
+
+	link := fmt.Sprintf("%s/-/feature_flags/%d/edit", e.Project.WebURL, e.ObjectAttributes.ID)
+	keyboard := object.NewMessagesKeyboardInline()
+	keyboard.AddRow()
+	keyboard.AddOpenLinkButton(link, "Open feature flag", "")
+
+	userID := getUserID(ctx)
+	s.sendMessage(userID, message, keyboard)
 }
 
 func (s *Service) onWikiPage(ctx context.Context, e gitlab.EventWikiPage) {
@@ -272,6 +623,10 @@ func (s *Service) onUnknow(ctx context.Context, e interface{}) {
 	event := getEventType(ctx)
 	message := fmt.Sprintf("❓ Unknown event %s", event)
 
+	if hook, ok := e.(*gitlab.EventSystemHook); ok && hook.EventName != "" {
+		message += " " + string(hook.EventName)
+	}
+
 	log.WithFields(log.Fields{
 		"userID": userID,
 		"event":  event,
@@ -281,61 +636,67 @@ func (s *Service) onUnknow(ctx context.Context, e interface{}) {
 }
 
 func (s *Service) sendMessage(peerID int, message string, keyboard *object.MessagesKeyboard) int {
+	return s.sendMessageMentions(peerID, message, keyboard, false)
+}
+
+// sendMessageMentions send message, mentions in message notify users only if
+// mentions is true
+func (s *Service) sendMessageMentions(
+	peerID int,
+	message string,
+	keyboard *object.MessagesKeyboard,
+	mentions bool,
+) int {
 	b := params.NewMessagesSendBuilder()
 	b.PeerID(peerID)
 	b.RandomID(0)
 	b.Message(message)
-	b.DisableMentions(true)
+	b.DisableMentions(!mentions)
 	b.DontParseLinks(true)
 
 	if keyboard != nil {
 		b.Keyboard(keyboard)
 	}
 
-	attempt := 0
-	for attempt < maxAttemptSendMessage {
-		retry := false
-
-		id, err := s.vk.MessagesSend(b.Params)
+	// too many requests are retried by rate limiter, other temporary errors
+	// are retried by outbox
+	id, err := s.vk.MessagesSend(b.Params)
 
-		var errCode api.ErrorType
+	errCode := vkErrorCode(err)
+	if chatGone(errCode) {
+		// webhook of chat is disabled, so message is not needed anymore
+		s.disableChat(peerID)
 
-		errors.As(err, &errCode)
+		return 0
+	}
 
-		switch errCode {
-		case api.ErrNoType:
-			if err != nil {
-				log.WithError(err).WithFields(log.Fields(b.Params)).Error("Messages send error")
-				return 0
-			}
+	permanent := false
 
+	switch errCode {
+	case api.ErrNoType:
+		if err == nil {
 			return id
-		case api.ErrTooMany:
-			log.WithError(err).WithFields(log.Fields(b.Params)).Warn("Retry send message")
-
-			retry = true
-		case api.ErrServer:
-			log.WithError(err).WithFields(log.Fields(b.Params)).Warn("Retry send message")
-
-			retry = true
-		case api.ErrMessagesDenySend:
-			log.WithError(err).WithFields(log.Fields(b.Params)).Info("Messages deny send")
-		default:
-			log.WithError(err).WithFields(log.Fields(b.Params)).Error("Messages send error")
 		}
 
-		if !retry {
-			break
-		}
+		log.WithError(err).WithFields(log.Fields(b.Params)).Error("Messages send error")
+	case api.ErrTooMany, api.ErrServer:
+		log.WithError(err).WithFields(log.Fields(b.Params)).Warn("Message saved to outbox")
+	case api.ErrMessagesDenySend:
+		log.WithError(err).WithFields(log.Fields(b.Params)).Info("Messages deny send")
+
+		permanent = true
+	default:
+		log.WithError(err).WithFields(log.Fields(b.Params)).Error("Messages send error")
 
-		time.Sleep(time.Second)
-		attempt++
+		permanent = true
 	}
 
+	s.saveUndelivered(peerID, message, keyboard, mentions, err, permanent)
+
 	return 0
 }
 
-func (s *Service) addMessage(peerID, messageID int, message string) error {
+func (s *Service) addMessage(peerID, messageID int, message string, keyboard *object.MessagesKeyboard) error {
 	if messageID == 0 {
 		return fmt.Errorf("message id=0")
 	}
@@ -360,18 +721,52 @@ func (s *Service) addMessage(peerID, messageID int, message string) error {
 	b.Message(message)
 	b.DontParseLinks(true)
 
+	if keyboard != nil {
+		b.Keyboard(keyboard)
+	}
+
 	_, err = s.vk.MessagesEdit(b.Params)
 
 	return err
 }
 
 func (s *Service) sendPipelineMessage(peerID int, message string, keyboard *object.MessagesKeyboard) {
-	id, _ := strconv.Atoi(s.getKey(peerID, pipelineMessageID))
+	s.sendChainMessage(peerID, pipelineMessageID, message, keyboard)
+}
+
+// isLastChain return true if chain with id is the last chain of peer. On
+// storage error new chain is started, so message is not lost.
+func (s *Service) isLastChain(peerID int, lastKey string, id int) bool {
+	lastID, err := s.getKey(peerID, lastKey)
+
+	return err == nil && lastID == strconv.Itoa(id)
+}
+
+// sendNewChainMessage send first message of chain and remember it. Storage
+// errors are logged by setKey, next message of the chain is sent as new
+// message in this case.
+func (s *Service) sendNewChainMessage(
+	peerID int,
+	lastKey string,
+	lastID int,
+	key, message string,
+	keyboard *object.MessagesKeyboard,
+) {
+	_ = s.setKey(peerID, lastKey, strconv.Itoa(lastID))
+	id := s.sendMessage(peerID, message, keyboard)
+	_ = s.setKey(peerID, key, strconv.Itoa(id))
+}
+
+// sendChainMessage append message to the message whose id is stored by key,
+// or send new message if it can't be edited
+func (s *Service) sendChainMessage(peerID int, key, message string, keyboard *object.MessagesKeyboard) {
+	value, _ := s.getKey(peerID, key)
+	id, _ := strconv.Atoi(value)
 
 	if id != 0 {
 		log.WithField("id", id).Debug("addMessage")
 
-		err := s.addMessage(peerID, id, message)
+		err := s.addMessage(peerID, id, message, keyboard)
 		if err == nil {
 			return
 		}
@@ -379,6 +774,6 @@ func (s *Service) sendPipelineMessage(peerID int, message string, keyboard *obje
 
 	id = s.sendMessage(peerID, message, keyboard)
 	if id != 0 {
-		s.setKey(peerID, pipelineMessageID, strconv.Itoa(id))
+		_ = s.setKey(peerID, key, strconv.Itoa(id))
 	}
 }
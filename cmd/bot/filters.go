@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/SevereCloud/gitlabvk/pkg/gitlab"
+	"github.com/SevereCloud/vksdk/v2/object"
+	log "github.com/sirupsen/logrus"
+)
+
+// eventKind is event type, which can be turned off by peer
+type eventKind struct {
+	Type  gitlab.EventType
+	Label string
+	// Name is argument of subscribe and unsubscribe commands
+	Name string
+}
+
+// eventKinds return event types in keyboard order
+func eventKinds() []eventKind {
+	return []eventKind{
+		{gitlab.EventTypePush, "Push", "push"},
+		{gitlab.EventTypeTagPush, "Tag", "tag"},
+		{gitlab.EventTypeIssue, "Issue", "issue"},
+		{gitlab.EventTypeNote, "Comment", "comment"},
+		{gitlab.EventTypeMergeRequest, "Merge request", "mr"},
+		{gitlab.EventTypeJob, "Job", "job"},
+		{gitlab.EventTypePipeline, "Pipeline", "pipeline"},
+		{gitlab.EventTypeWikiPage, "Wiki", "wiki"},
+		{gitlab.EventTypeDeployment, "Deployment", "deployment"},
+		{gitlab.EventTypeRelease, "Release", "release"},
+		{gitlab.EventTypeMember, "Member", "member"},
+		{gitlab.EventTypeSubgroup, "Subgroup", "subgroup"},
+		{gitlab.EventTypeProject, "Project", "project"},
+		{gitlab.EventTypeFeatureFlag, "Feature flag", "feature_flag"},
+	}
+}
+
+// isEventKind return true if t can be turned off
+func isEventKind(t gitlab.EventType) bool {
+	for _, kind := range eventKinds() {
+		if kind.Type == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// eventKindsByName return event types by names of subscribe command. Name
+// "all" selects all types.
+func eventKindsByName(names []string) ([]gitlab.EventType, error) {
+	var types []gitlab.EventType
+
+	for _, name := range names {
+		name = strings.ToLower(name)
+		if name == "all" || name == "все" {
+			all := make([]gitlab.EventType, 0, len(eventKinds()))
+			for _, kind := range eventKinds() {
+				all = append(all, kind.Type)
+			}
+
+			return all, nil
+		}
+
+		found := false
+
+		for _, kind := range eventKinds() {
+			if kind.Name == name || strings.EqualFold(kind.Label, name) {
+				types = append(types, kind.Type)
+				found = true
+
+				break
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("%w %q", errBadPattern, name)
+		}
+	}
+
+	return types, nil
+}
+
+// eventKindOf return event type, which controls t. Confidential events are
+// controlled by their public types.
+func eventKindOf(t gitlab.EventType) gitlab.EventType {
+	switch t {
+	case gitlab.EventTypeConfidentialIssue:
+		return gitlab.EventTypeIssue
+	case gitlab.EventConfidentialTypeNote:
+		return gitlab.EventTypeNote
+	default:
+		return t
+	}
+}
+
+// disabledEvents return event types turned off by peer
+func (s *Service) disabledEvents(peerID int, hook string) ([]gitlab.EventType, error) {
+	value, err := s.getKey(peerID, hookKey(disabledEventsKey, hook))
+	if err != nil {
+		return nil, err
+	}
+
+	return parseEventTypes(value), nil
+}
+
+// parseEventTypes return event types from comma separated list
+func parseEventTypes(value string) []gitlab.EventType {
+	if value == "" {
+		return nil
+	}
+
+	var types []gitlab.EventType
+
+	for _, t := range strings.Split(value, ",") {
+		types = append(types, gitlab.EventType(t))
+	}
+
+	return types
+}
+
+// eventEnabled return true if peer wants events of type t
+func (s *Service) eventEnabled(peerID int, hook string, t gitlab.EventType) (bool, error) {
+	disabled, err := s.disabledEvents(peerID, hook)
+	if err != nil {
+		return false, err
+	}
+
+	t = eventKindOf(t)
+
+	for _, v := range disabled {
+		if v == t {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// toggleEventType turn event type on or off and return new state
+func (s *Service) toggleEventType(peerID int, hook string, t gitlab.EventType) (bool, error) {
+	enabled := true
+
+	err := s.updateKey(peerID, hookKey(disabledEventsKey, hook), func(old string) (string, error) {
+		var values []string
+
+		enabled = false
+
+		for _, v := range parseEventTypes(old) {
+			if v == t {
+				enabled = true
+				continue
+			}
+
+			values = append(values, string(v))
+		}
+
+		if !enabled {
+			values = append(values, string(t))
+		}
+
+		return strings.Join(values, ","), nil
+	})
+
+	return enabled, err
+}
+
+// setEventsEnabled turn event types on or off
+func (s *Service) setEventsEnabled(peerID int, hook string, types []gitlab.EventType, enabled bool) error {
+	return s.updateKey(peerID, hookKey(disabledEventsKey, hook), func(old string) (string, error) {
+		var values []string
+
+		for _, v := range parseEventTypes(old) {
+			if !containsEventType(types, v) {
+				values = append(values, string(v))
+			}
+		}
+
+		if !enabled {
+			for _, t := range types {
+				values = append(values, string(t))
+			}
+		}
+
+		return strings.Join(values, ","), nil
+	})
+}
+
+func containsEventType(types []gitlab.EventType, t gitlab.EventType) bool {
+	for _, v := range types {
+		if v == t {
+			return true
+		}
+	}
+
+	return false
+}
+
+// eventKeyboardBuild return keyboard with event type toggles
+func (s *Service) eventKeyboardBuild(peerID int, hook string) *object.MessagesKeyboard {
+	// labels are built even if storage is unavailable
+	disabled, _ := s.disabledEvents(peerID, hook)
+
+	keyboard := object.NewMessagesKeyboard(true)
+
+	for i, kind := range eventKinds() {
+		if i%2 == 0 {
+			keyboard.AddRow()
+		}
+
+		label := "✅ " + kind.Label
+
+		for _, v := range disabled {
+			if v == kind.Type {
+				label = "❌ " + kind.Label
+				break
+			}
+		}
+
+		keyboard.AddTextButton(
+			label,
+			ButtonPayload{
+				Command: toggleEvent,
+				Payload: string(kind.Type),
+				Hook:    hook,
+			}.String(),
+			"",
+		)
+	}
+
+	back := ButtonPayload{Command: getSetting}
+	if hook != "" {
+		back = ButtonPayload{Command: hookMenu, Hook: hook}
+	}
+
+	keyboard.AddRow().AddTextButton(
+		"Назад",
+		back.String(),
+		"",
+	)
+
+	return keyboard
+}
+
+// eventsMessageBuild return list of event types turned off by peer
+func (s *Service) eventsMessageBuild(peerID int, hook string) (string, error) {
+	disabled, err := s.disabledEvents(peerID, hook)
+	if err != nil {
+		return "", err
+	}
+
+	var labels []string
+
+	for _, kind := range eventKinds() {
+		for _, v := range disabled {
+			if v == kind.Type {
+				labels = append(labels, kind.Label)
+			}
+		}
+	}
+
+	if len(labels) == 0 {
+		return "Выключенные события: нет\n", nil
+	}
+
+	return "Выключенные события: " + strings.Join(labels, ", ") + "\n", nil
+}
+
+// filterMiddleware drop events of muted peer, events, which are turned off
+// by peer, events of refs, which don't match ref patterns, and issues and
+// merge requests, which don't match issue filter of peer
+func (s *Service) filterMiddleware(next gitlab.HandlerFunc) gitlab.HandlerFunc {
+	return func(ctx context.Context, e gitlab.Event) error {
+		userID := getUserID(ctx)
+		hook := getHookID(ctx)
+
+		if until, err := s.mutedUntil(userID); err == nil && !until.IsZero() {
+			log.WithFields(log.Fields{
+				"userID": userID,
+				"event":  e.Type,
+			}).Debug("peer muted")
+
+			return nil
+		}
+
+		enabled, err := s.eventEnabled(userID, hook, e.Type)
+		if err != nil {
+			// storage error is already logged, send event anyway
+			return next(ctx, e)
+		}
+
+		if !enabled {
+			log.WithFields(log.Fields{
+				"userID": userID,
+				"hook":   hook,
+				"event":  e.Type,
+			}).Debug("event type disabled")
+
+			return nil
+		}
+
+		if ref, ok := eventRef(e.Value); ok {
+			patterns, err := s.refPatterns(userID, hook)
+			if err == nil && !matchRef(patterns, ref) {
+				log.WithFields(log.Fields{
+					"userID": userID,
+					"hook":   hook,
+					"event":  e.Type,
+					"ref":    ref,
+				}).Debug("ref filtered")
+
+				return nil
+			}
+		}
+
+		if sub, ok := filterSubjectOf(e.Value); ok {
+			filter, err := s.issueFilter(userID, hook)
+			if err == nil && !filter.Match(sub) {
+				log.WithFields(log.Fields{
+					"userID": userID,
+					"hook":   hook,
+					"event":  e.Type,
+				}).Debug("issue filtered")
+
+				return nil
+			}
+		}
+
+		return next(ctx, e)
+	}
+}
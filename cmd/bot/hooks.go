@@ -0,0 +1,457 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/SevereCloud/vksdk/v2/object"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// maxHooks is limited by keyboard size: VK allows 10 rows, hooks take 2
+	// per row and 2 rows are for buttons
+	maxHooks      = 16
+	maxHookName   = 32
+	hookIDLength  = 8
+	hookNameShort = 20
+)
+
+// webhook errors
+var (
+	errHookNotFound = errors.New("webhook not found")
+	errHookLimit    = errors.New("too many webhooks")
+	errHookName     = errors.New("empty webhook name")
+)
+
+// webhook is named webhook of peer. Default webhook has empty ID and is not
+// stored in the list.
+type webhook struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// hookKey return storage key of hook setting. Settings of default webhook
+// use key as is.
+func hookKey(key, hook string) string {
+	if hook == "" {
+		return key
+	}
+
+	return key + "_" + hook
+}
+
+// hookKeys are keys with settings of webhook
+func hookKeys() []string {
+	return []string{"salt", disabledEventsKey, refFiltersKey, issueFilterKey}
+}
+
+// hookArg return argument of text commands, which selects webhook
+func hookArg(hook string) string {
+	if hook == "" {
+		return ""
+	}
+
+	return "#" + hook + " "
+}
+
+// validHookID return true if id can be hook id
+func validHookID(id string) bool {
+	if len(id) != hookIDLength {
+		return false
+	}
+
+	for _, r := range id {
+		if !('a' <= r && r <= 'z' || 'A' <= r && r <= 'Z' || '0' <= r && r <= '9') {
+			return false
+		}
+	}
+
+	return true
+}
+
+// hooks return named webhooks of peer
+func (s *Service) hooks(peerID int) ([]webhook, error) {
+	value, err := s.getKey(peerID, hooksKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseHooks(value)
+}
+
+// parseHooks return webhooks from stored value
+func parseHooks(value string) ([]webhook, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var hooks []webhook
+	if err := json.Unmarshal([]byte(value), &hooks); err != nil {
+		return nil, err
+	}
+
+	return hooks, nil
+}
+
+// formatHooks return stored value of webhooks
+func formatHooks(hooks []webhook) (string, error) {
+	if len(hooks) == 0 {
+		return "", nil
+	}
+
+	raw, err := json.Marshal(hooks)
+
+	return string(raw), err
+}
+
+// updateHooks change list of webhooks of peer atomically
+func (s *Service) updateHooks(peerID int, update func(hooks []webhook) ([]webhook, error)) error {
+	return s.updateKey(peerID, hooksKey, func(old string) (string, error) {
+		hooks, err := parseHooks(old)
+		if err != nil {
+			return "", err
+		}
+
+		if hooks, err = update(hooks); err != nil {
+			return "", err
+		}
+
+		return formatHooks(hooks)
+	})
+}
+
+// findHook return named webhook of peer. Empty id is default webhook.
+func (s *Service) findHook(peerID int, id string) (webhook, bool, error) {
+	if id == "" {
+		return webhook{Name: "Основной"}, true, nil
+	}
+
+	hooks, err := s.hooks(peerID)
+	if err != nil {
+		return webhook{}, false, err
+	}
+
+	for _, h := range hooks {
+		if h.ID == id {
+			return h, true, nil
+		}
+	}
+
+	return webhook{}, false, nil
+}
+
+// cleanHookName return name without line breaks and limited by length
+func cleanHookName(name string) string {
+	name = strings.Join(strings.Fields(name), " ")
+
+	if r := []rune(name); len(r) > maxHookName {
+		name = string(r[:maxHookName])
+	}
+
+	return name
+}
+
+// addHook add named webhook to peer
+func (s *Service) addHook(peerID int, name string) (webhook, error) {
+	var h webhook
+
+	err := s.updateHooks(peerID, func(hooks []webhook) ([]webhook, error) {
+		if len(hooks) >= maxHooks {
+			return nil, errHookLimit
+		}
+
+		h = webhook{
+			ID:   GenerateRandomString(hookIDLength),
+			Name: cleanHookName(name),
+		}
+
+		if h.Name == "" {
+			h.Name = "Webhook " + strconv.Itoa(len(hooks)+1)
+		}
+
+		return append(hooks, h), nil
+	})
+
+	return h, err
+}
+
+// setHookName change name of webhook
+func (s *Service) setHookName(peerID int, id, name string) error {
+	name = cleanHookName(name)
+	if name == "" {
+		return errHookName
+	}
+
+	return s.updateHooks(peerID, func(hooks []webhook) ([]webhook, error) {
+		for i := range hooks {
+			if hooks[i].ID == id {
+				hooks[i].Name = name
+				return hooks, nil
+			}
+		}
+
+		return nil, errHookNotFound
+	})
+}
+
+// removeHook remove webhook and its settings. Token of webhook becomes
+// invalid.
+func (s *Service) removeHook(peerID int, id string) error {
+	err := s.updateHooks(peerID, func(hooks []webhook) ([]webhook, error) {
+		for i := range hooks {
+			if hooks[i].ID == id {
+				return append(hooks[:i], hooks[i+1:]...), nil
+			}
+		}
+
+		return nil, errHookNotFound
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range hookKeys() {
+		if err := s.setKey(peerID, hookKey(key, id), ""); err != nil {
+			log.WithError(err).WithField("peer_id", peerID).Warn("Webhook setting not removed")
+		}
+	}
+
+	return nil
+}
+
+// shortName return name for button
+func shortName(name string) string {
+	if r := []rune(name); len(r) > hookNameShort {
+		return string(r[:hookNameShort-1]) + "…"
+	}
+
+	return name
+}
+
+// hooksMessageBuild return list of named webhooks
+func (s *Service) hooksMessageBuild(peerID int) (string, error) {
+	hooks, err := s.hooks(peerID)
+	if err != nil {
+		return "", err
+	}
+
+	text := "Webhooks:\n- Основной\n"
+	for _, h := range hooks {
+		text += "- " + h.Name + " (" + h.ID + ")\n"
+	}
+
+	text += "\nСоздать: /hook new название\n" +
+		"Переименовать: /hook rename id название\n" +
+		"Удалить: /hook delete id"
+
+	return text, nil
+}
+
+// hooksKeyboardBuild return keyboard with list of webhooks
+func (s *Service) hooksKeyboardBuild(peerID int) *object.MessagesKeyboard {
+	hooks, _ := s.hooks(peerID)
+
+	keyboard := object.NewMessagesKeyboard(true)
+
+	for i, h := range hooks {
+		if i%2 == 0 {
+			keyboard.AddRow()
+		}
+
+		keyboard.AddTextButton(
+			shortName(h.Name),
+			ButtonPayload{
+				Command: hookMenu,
+				Hook:    h.ID,
+			}.String(),
+			"",
+		)
+	}
+
+	if len(hooks) < maxHooks {
+		keyboard.AddRow().AddTextButton(
+			"Создать webhook",
+			ButtonPayload{
+				Command: createHook,
+			}.String(),
+			"positive",
+		)
+	}
+
+	keyboard.AddRow().AddTextButton(
+		"Назад",
+		ButtonPayload{
+			Command: getSetting,
+		}.String(),
+		"",
+	)
+
+	return keyboard
+}
+
+// hookKeyboardBuild return keyboard of named webhook
+func (s *Service) hookKeyboardBuild(hook string) *object.MessagesKeyboard {
+	keyboard := object.NewMessagesKeyboard(true)
+	keyboard.AddRow().AddTextButton(
+		"Настройки webhook",
+		ButtonPayload{
+			Command: getSetting,
+			Hook:    hook,
+		}.String(),
+		"",
+	).AddTextButton(
+		"Сбросить ключ",
+		ButtonPayload{
+			Command: resetToken,
+			Hook:    hook,
+		}.String(),
+		"negative",
+	)
+	keyboard.AddRow().AddTextButton(
+		"Типы событий",
+		ButtonPayload{
+			Command: eventSettings,
+			Hook:    hook,
+		}.String(),
+		"",
+	)
+	keyboard.AddRow().AddTextButton(
+		"Фильтр веток",
+		ButtonPayload{
+			Command: refFilters,
+			Hook:    hook,
+		}.String(),
+		"",
+	).AddTextButton(
+		"Фильтр issue и MR",
+		ButtonPayload{
+			Command: issueFilters,
+			Hook:    hook,
+		}.String(),
+		"",
+	)
+	keyboard.AddRow().AddTextButton(
+		"Удалить webhook",
+		ButtonPayload{
+			Command: deleteHook,
+			Hook:    hook,
+		}.String(),
+		"negative",
+	)
+	keyboard.AddRow().AddTextButton(
+		"Назад",
+		ButtonPayload{
+			Command: hookList,
+		}.String(),
+		"",
+	)
+
+	return keyboard
+}
+
+// deleteKeyboardBuild return keyboard with confirmation of webhook removal
+func deleteKeyboardBuild(hook string) *object.MessagesKeyboard {
+	keyboard := object.NewMessagesKeyboard(true)
+	keyboard.AddRow().AddTextButton(
+		"Да, удалить",
+		ButtonPayload{
+			Command: deleteHookConfirm,
+			Hook:    hook,
+		}.String(),
+		"negative",
+	).AddTextButton(
+		"Отмена",
+		ButtonPayload{
+			Command: hookMenu,
+			Hook:    hook,
+		}.String(),
+		"",
+	)
+
+	return keyboard
+}
+
+// menuKeyboardBuild return main keyboard or keyboard of named webhook
+func (s *Service) menuKeyboardBuild(peerID int, hook string) *object.MessagesKeyboard {
+	if hook == "" {
+		return s.KeyboardBuild(peerID)
+	}
+
+	return s.hookKeyboardBuild(hook)
+}
+
+// hookCommand handle commands of webhook management
+func (s *Service) hookCommand(peerID int, p ButtonPayload, fields log.Fields) (
+	message string,
+	keyboard *object.MessagesKeyboard,
+	err error,
+) {
+	fields["hook"] = p.Hook
+
+	// default webhook can't be renamed or deleted
+	if p.Hook == "" && p.Command != createHook && p.Command != hookList {
+		p.Command = hookList
+	}
+
+	switch p.Command {
+	case createHook:
+		var h webhook
+
+		h, err = s.addHook(peerID, p.Payload)
+		if err != nil {
+			break
+		}
+
+		log.WithFields(fields).WithField("hook", h.ID).Info("User create webhook")
+
+		message = "Webhook «" + h.Name + "» создан, id " + h.ID + "\n" +
+			"Переименовать: /hook rename " + h.ID + " название"
+		keyboard = s.hookKeyboardBuild(h.ID)
+	case renameHook:
+		log.WithFields(fields).Info("User rename webhook")
+
+		if err = s.setHookName(peerID, p.Hook, p.Payload); err == nil {
+			message = "Webhook переименован"
+			keyboard = s.hookKeyboardBuild(p.Hook)
+		}
+	case deleteHook:
+		message = "Удалить webhook? Его токен перестанет работать"
+		keyboard = deleteKeyboardBuild(p.Hook)
+	case deleteHookConfirm:
+		log.WithFields(fields).Info("User delete webhook")
+
+		if err = s.removeHook(peerID, p.Hook); err == nil {
+			message, err = s.hooksMessageBuild(peerID)
+			message = "Webhook удален\n\n" + message
+		}
+	case hookMenu:
+		var h webhook
+
+		h, _, err = s.findHook(peerID, p.Hook)
+		message = "Webhook «" + h.Name + "», id " + h.ID
+		keyboard = s.hookKeyboardBuild(p.Hook)
+	default:
+		message, err = s.hooksMessageBuild(peerID)
+	}
+
+	switch {
+	case errors.Is(err, errHookLimit):
+		message = "Можно создать не больше " + strconv.Itoa(maxHooks) + " webhook"
+		err = nil
+	case errors.Is(err, errHookName):
+		message = "Укажите название: /hook rename " + p.Hook + " название"
+		err = nil
+	case errors.Is(err, errHookNotFound):
+		message = "Webhook не найден"
+		err = nil
+	}
+
+	if keyboard == nil {
+		keyboard = s.hooksKeyboardBuild(peerID)
+	}
+
+	return message, keyboard, err
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+const day = 24 * time.Hour
+
+// humanDuration return short duration like "1h 5m" or "42s"
+func humanDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+
+	switch {
+	case d >= day:
+		return fmt.Sprintf("%dd %dh", d/day, (d%day)/time.Hour)
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh %dm", d/time.Hour, (d%time.Hour)/time.Minute)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm %ds", d/time.Minute, (d%time.Minute)/time.Second)
+	default:
+		return fmt.Sprintf("%ds", d/time.Second)
+	}
+}
+
+// relativeTime return time relative to now like "in 2d 3h" or "5m 2s ago"
+func relativeTime(t time.Time) string {
+	d := time.Until(t)
+	if d < 0 {
+		return humanDuration(-d) + " ago"
+	}
+
+	return "in " + humanDuration(d)
+}
+
+// relativeDate return date relative to today like "today", "in 3 days"
+// or "2 days ago"
+func relativeDate(t time.Time) string {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	days := int(t.Sub(today) / day)
+
+	switch {
+	case days == 0:
+		return "today"
+	case days == 1:
+		return "tomorrow"
+	case days == -1:
+		return "yesterday"
+	case days > 0:
+		return fmt.Sprintf("in %d days", days)
+	default:
+		return fmt.Sprintf("%d days ago", -days)
+	}
+}
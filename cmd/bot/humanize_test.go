@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHumanDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0s"},
+		{42 * time.Second, "42s"},
+		{65 * time.Second, "1m 5s"},
+		{time.Hour + 5*time.Minute + 30*time.Second, "1h 5m"},
+		{2*day + 3*time.Hour, "2d 3h"},
+	}
+
+	for _, tt := range tests {
+		if got := humanDuration(tt.d); got != tt.want {
+			t.Errorf("humanDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	if got := relativeTime(time.Now().Add(2*day + 3*time.Hour + time.Second)); got != "in 2d 3h" {
+		t.Errorf("relativeTime() = %q, want %q", got, "in 2d 3h")
+	}
+
+	if got := relativeTime(time.Now().Add(-5 * time.Minute)); got != "5m 0s ago" {
+		t.Errorf("relativeTime() = %q, want %q", got, "5m 0s ago")
+	}
+}
+
+func TestRelativeDate(t *testing.T) {
+	now := time.Now().UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		days int
+		want string
+	}{
+		{0, "today"},
+		{1, "tomorrow"},
+		{-1, "yesterday"},
+		{3, "in 3 days"},
+		{-2, "2 days ago"},
+	}
+
+	for _, tt := range tests {
+		if got := relativeDate(today.AddDate(0, 0, tt.days)); got != tt.want {
+			t.Errorf("relativeDate(%+d) = %q, want %q", tt.days, got, tt.want)
+		}
+	}
+}
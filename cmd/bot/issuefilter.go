@@ -0,0 +1,297 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/SevereCloud/gitlabvk/pkg/gitlab"
+)
+
+// clearIssueFilter is argument of filter command, which removes filter
+const clearIssueFilter = "-"
+
+// issue filter fields
+const (
+	filterLabel    = "label"
+	filterAuthor   = "author"
+	filterUser     = "user"
+	filterAssignee = "assignee"
+	filterTarget   = "target"
+)
+
+// filterTerm is condition like label:bug or !assignee:john
+type filterTerm struct {
+	not   bool
+	field string
+	value string
+}
+
+// issueFilter is OR of groups, every group is AND of terms
+type issueFilter [][]filterTerm
+
+// filterSubject is fields of issue or merge request, which can be filtered.
+// User is actor of event. GitLab sends only id of author, so author is
+// matched by id.
+type filterSubject struct {
+	labels    []string
+	user      string
+	authorID  int
+	assignees []string
+	target    string
+}
+
+// splitTerms split text by spaces outside of double quotes
+func splitTerms(text string) []string {
+	var (
+		terms  []string
+		term   strings.Builder
+		quoted bool
+	)
+
+	for _, r := range text {
+		switch {
+		case r == '"':
+			quoted = !quoted
+		case (r == ' ' || r == '\n' || r == '\t') && !quoted:
+			if term.Len() > 0 {
+				terms = append(terms, term.String())
+				term.Reset()
+			}
+		default:
+			term.WriteRune(r)
+		}
+	}
+
+	if term.Len() > 0 {
+		terms = append(terms, term.String())
+	}
+
+	return terms
+}
+
+// parseIssueFilter parse filter like "label:bug user:john | target:release/*".
+// Terms separated by space must all match, groups separated by | are
+// alternatives.
+func parseIssueFilter(text string) (issueFilter, error) {
+	var filter issueFilter
+
+	for _, group := range strings.Split(text, "|") {
+		var terms []filterTerm
+
+		for _, raw := range splitTerms(group) {
+			t := filterTerm{}
+
+			if strings.HasPrefix(raw, "!") {
+				t.not = true
+				raw = raw[1:]
+			}
+
+			i := strings.Index(raw, ":")
+			if i <= 0 || i == len(raw)-1 {
+				return nil, fmt.Errorf("%w %q", errBadPattern, raw)
+			}
+
+			t.field, t.value = strings.ToLower(raw[:i]), raw[i+1:]
+
+			switch t.field {
+			case filterLabel, filterUser, filterAssignee:
+			case filterAuthor:
+				if _, err := strconv.Atoi(t.value); err != nil {
+					return nil, fmt.Errorf("%w %q", errBadPattern, raw)
+				}
+			case filterTarget:
+				if _, err := path.Match(t.value, ""); err != nil {
+					return nil, fmt.Errorf("%w %q", errBadPattern, raw)
+				}
+			default:
+				return nil, fmt.Errorf("%w %q", errBadPattern, raw)
+			}
+
+			terms = append(terms, t)
+		}
+
+		if len(terms) > 0 {
+			filter = append(filter, terms)
+		}
+	}
+
+	return filter, nil
+}
+
+// String return filter in the form accepted by parseIssueFilter
+func (f issueFilter) String() string {
+	groups := make([]string, 0, len(f))
+
+	for _, terms := range f {
+		raw := make([]string, 0, len(terms))
+
+		for _, t := range terms {
+			term := t.field + ":" + t.value
+			if strings.ContainsAny(t.value, " \t\n") {
+				term = t.field + `:"` + t.value + `"`
+			}
+
+			if t.not {
+				term = "!" + term
+			}
+
+			raw = append(raw, term)
+		}
+
+		groups = append(groups, strings.Join(raw, " "))
+	}
+
+	return strings.Join(groups, " | ")
+}
+
+// Match return true if subject matches all terms of at least one group.
+// Empty filter matches everything.
+func (f issueFilter) Match(sub filterSubject) bool {
+	if len(f) == 0 {
+		return true
+	}
+
+	for _, terms := range f {
+		matched := true
+
+		for _, t := range terms {
+			if t.match(sub) == t.not {
+				matched = false
+				break
+			}
+		}
+
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (t filterTerm) match(sub filterSubject) bool {
+	switch t.field {
+	case filterLabel:
+		return containsFold(sub.labels, t.value)
+	case filterAuthor:
+		id, err := strconv.Atoi(t.value)
+		return err == nil && id == sub.authorID
+	case filterUser:
+		return strings.EqualFold(strings.TrimPrefix(t.value, "@"), sub.user)
+	case filterAssignee:
+		return containsFold(sub.assignees, strings.TrimPrefix(t.value, "@"))
+	case filterTarget:
+		ok, _ := path.Match(t.value, sub.target)
+		return ok && sub.target != ""
+	default:
+		return false
+	}
+}
+
+func containsFold(list []string, value string) bool {
+	for _, v := range list {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func labelNames(labels []gitlab.Label) []string {
+	names := make([]string, 0, len(labels))
+	for _, l := range labels {
+		names = append(names, l.Name)
+	}
+
+	return names
+}
+
+// filterSubjectOf return filter subject of issue or merge request event
+func filterSubjectOf(v interface{}) (filterSubject, bool) {
+	switch e := v.(type) {
+	case *gitlab.EventIssue:
+		sub := filterSubject{
+			labels:   labelNames(e.Labels),
+			user:     e.User.Username,
+			authorID: e.ObjectAttributes.AuthorID,
+		}
+
+		for _, a := range e.Assignees {
+			sub.assignees = append(sub.assignees, a.Username)
+		}
+
+		if e.Assignee.Username != "" {
+			sub.assignees = append(sub.assignees, e.Assignee.Username)
+		}
+
+		return sub, true
+	case *gitlab.EventMergeRequest:
+		sub := filterSubject{
+			labels:   labelNames(e.Labels),
+			user:     e.User.Username,
+			authorID: e.ObjectAttributes.AuthorID,
+			target:   e.ObjectAttributes.TargetBranch,
+		}
+
+		for _, a := range e.Assignees {
+			sub.assignees = append(sub.assignees, a.Username)
+		}
+
+		for _, a := range []gitlab.MergeAssignee{e.Assignee, e.ObjectAttributes.Assignee} {
+			if a.Username != "" {
+				sub.assignees = append(sub.assignees, a.Username)
+			}
+		}
+
+		return sub, true
+	default:
+		return filterSubject{}, false
+	}
+}
+
+// issueFilter return issue and merge request filter of peer
+func (s *Service) issueFilter(peerID int, hook string) (issueFilter, error) {
+	value, err := s.getKey(peerID, hookKey(issueFilterKey, hook))
+	if err != nil || value == "" {
+		return nil, err
+	}
+
+	return parseIssueFilter(value)
+}
+
+// setIssueFilter validate and save filter
+func (s *Service) setIssueFilter(peerID int, hook, text string) error {
+	filter, err := parseIssueFilter(text)
+	if err != nil {
+		return err
+	}
+
+	return s.setKey(peerID, hookKey(issueFilterKey, hook), filter.String())
+}
+
+// issueFilterMessageBuild return filter of peer with help
+func (s *Service) issueFilterMessageBuild(peerID int, hook string) (string, error) {
+	filter, err := s.issueFilter(peerID, hook)
+	if err != nil {
+		return "", err
+	}
+
+	text := "Фильтр issue и merge request: "
+	if len(filter) == 0 {
+		text += "все\n"
+	} else {
+		text += filter.String() + "\n"
+	}
+
+	text += "\nУсловия: label:, author: (ID автора в GitLab), user: (кто совершил действие), assignee:, " +
+		"target: (ветка merge request). " +
+		"Условия через пробел должны выполняться все, группы через | — хотя бы одна. " +
+		"! перед условием отрицает его.\n" +
+		"Изменить: /filter " + hookArg(hook) + "label:bug | target:release/*\n" +
+		"Сбросить: /filter " + hookArg(hook) + clearIssueFilter
+
+	return text, nil
+}
@@ -3,13 +3,17 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"expvar"
 	"flag"
-	"io/ioutil"
 	"net/http"
 	"net/url"
 	"os"
+	"os/signal"
 	"strconv"
-	"sync"
+	"strings"
+	"syscall"
+	"time"
 
 	"github.com/SevereCloud/gitlabvk/internal"
 	"github.com/SevereCloud/gitlabvk/pkg/gitlab"
@@ -24,6 +28,28 @@ import (
 const (
 	getSetting         = "get_setting"
 	resetToken         = "reset_token"
+	toggleConfidential = "toggle_confidential"
+	toggleEmoji        = "toggle_emoji"
+	setEmoji           = "emoji"
+	eventSettings      = "event_settings"
+	toggleEvent        = "toggle_event"
+	refFilters         = "ref_filters"
+	issueFilters       = "issue_filters"
+	hookList           = "hook_list"
+	hookMenu           = "hook_menu"
+	createHook         = "create_hook"
+	renameHook         = "rename_hook"
+	deleteHook         = "delete_hook"
+	deleteHookConfirm  = "delete_hook_confirm"
+	linkAccount        = "link_account"
+	unlinkAccount      = "unlink_account"
+	toggleMentions     = "toggle_mentions"
+	subscribeEvents    = "subscribe"
+	unsubscribeEvents  = "unsubscribe"
+	showFilters        = "filters"
+	muteNotifications  = "mute"
+	showStatus         = "status"
+	showHelp           = "help"
 	notSupportedButton = "not_supported_button"
 )
 
@@ -37,6 +63,7 @@ type ButtonPayload struct {
 	ButtonType string `json:"button_type,omitempty"`
 	Command    string `json:"command"`
 	Payload    string `json:"payload,omitempty"`
+	Hook       string `json:"hook,omitempty"`
 }
 
 func (b ButtonPayload) String() string {
@@ -46,15 +73,40 @@ func (b ButtonPayload) String() string {
 
 const maxContentLength = 1e6 * 5 // 5 Mbyte
 
+const (
+	defaultDedupWindow = time.Hour
+	defaultDedupFile   = "gitlabvk_dedup.log"
+
+	defaultQueueWorkers  = 8
+	defaultQueueSize     = 100
+	defaultQueueCapacity = 10000
+
+	shutdownTimeout = 30 * time.Second
+)
+
 // Service struct
 type Service struct {
 	fl *gitlab.FuncList
 	vk *api.VK
 	cb *callback.Callback
 
-	verify       *internal.Verification
-	mtx          sync.Mutex
-	storageCache map[string]string
+	webhook *gitlab.WebhookHandler
+	dedup   *internal.Dedup
+	queue   *internal.Queue
+	outbox  *internal.Outbox
+
+	stopOutbox context.CancelFunc
+
+	adminFl      *gitlab.FuncList
+	adminWebhook *gitlab.WebhookHandler
+	adminPeerID  int
+
+	verify  *internal.Verification
+	storage internal.Storage
+	cache   *internal.Cache
+	keyLock *internal.KeyLock
+
+	metrics *expvar.Map
 
 	domain string
 }
@@ -62,16 +114,19 @@ type Service struct {
 // NewService return new *Service
 func NewService(domain string) *Service {
 	s := &Service{
-		fl:           gitlab.NewFuncList(),
-		vk:           api.NewVK(os.Getenv("GITLABVK_ACCESS_TOKEN")),
-		cb:           callback.NewCallback(),
-		storageCache: make(map[string]string),
-		domain:       domain,
+		fl:      gitlab.NewFuncList(),
+		vk:      api.NewVK(os.Getenv("GITLABVK_ACCESS_TOKEN")),
+		cb:      callback.NewCallback(),
+		cache:   newCache(),
+		keyLock: internal.NewKeyLock(),
+		metrics: expvar.NewMap("gitlabvk"),
+		domain:  domain,
 	}
 	s.cb.MessageNew(s.MessageNew)
 
 	s.vk.EnableMessagePack()
 	s.vk.EnableZstd()
+	s.initRateLimit()
 
 	tokenPerm, err := s.vk.GroupsGetTokenPermissions(api.Params{})
 	if err != nil {
@@ -84,32 +139,178 @@ func NewService(domain string) *Service {
 		log.WithField("permissions", tokenPerm.Permissions).Fatal("Token bad permissions. Need messages and manage")
 	}
 
+	s.storage, err = s.newStorage()
+	if err != nil {
+		log.WithError(err).Fatal("Storage init error")
+	}
+
 	// Храним ключ у пользователя 2e9
-	secret := s.getKey(2e9, "secret")
-	if secret == "" {
-		secret = GenerateRandomString(32)
-		s.setKey(2e9, "secret", secret)
+	secret, err := s.getOrSetKey(2e9, "secret", func() string {
+		return GenerateRandomString(32)
+	})
+	if err != nil {
+		log.WithError(err).Fatal("Secret load error")
 	}
 
 	s.verify = internal.NewVerification(secret)
 
+	s.initDedup()
+
+	outbox, err := internal.NewOutbox(outboxDir())
+	if err != nil {
+		log.WithError(err).Fatal("Outbox init error")
+	}
+
+	s.outbox = outbox
+
+	var outboxCtx context.Context
+
+	outboxCtx, s.stopOutbox = context.WithCancel(context.Background())
+	go s.runOutbox(outboxCtx)
+
+	s.initEvents()
+	s.initAdmin()
+
+	return s
+}
+
+// initEvents init queue and handlers of GitLab events
+func (s *Service) initEvents() {
+	s.queue = internal.NewQueue(
+		envInt("GITLABVK_QUEUE_WORKERS", defaultQueueWorkers),
+		envInt("GITLABVK_QUEUE_SIZE", defaultQueueSize),
+		envInt("GITLABVK_QUEUE_CAPACITY", defaultQueueCapacity),
+	)
+
+	s.fl.Use(s.queueMiddleware, logMiddleware, gitlab.Recoverer, s.verifyMiddleware, s.filterMiddleware)
+	s.webhook = gitlab.NewWebhookHandler(
+		s.fl,
+		gitlab.WithTokenVerifier(gitlab.TokenVerifierFunc(s.verifyToken)),
+		gitlab.WithMaxBodySize(maxContentLength),
+		gitlab.WithDeduplicator(s.dedup),
+		gitlab.WithErrorLog(webhookErrorLog),
+	)
+
 	// s.fl.OnBuild(s.onBuild)
+	s.fl.OnConfidentialIssue(s.onConfidentialIssue)
+	s.fl.OnConfidentialNote(s.onConfidentialNote)
+	s.fl.OnDeployment(s.onDeployment)
+	s.fl.OnEmoji(s.onEmoji)
+	s.fl.OnFeatureFlag(s.onFeatureFlag)
 	s.fl.OnIssue(s.onIssue)
 	s.fl.OnJob(s.onJob)
+	s.fl.OnMember(s.onMember)
 	s.fl.OnMergeRequest(s.onMergeRequest)
 	s.fl.OnNote(s.onNote)
 	s.fl.OnPipeline(s.onPipeline)
+	s.fl.OnProject(s.onProject)
 	s.fl.OnPush(s.onPush)
-	// s.fl.OnSystemHook(s.onSystemHook)
+	s.fl.OnRelease(s.onRelease)
+	s.fl.OnSubgroup(s.onSubgroup)
 	s.fl.OnTagPush(s.onTagPush)
 	s.fl.OnWikiPage(s.onWikiPage)
 	s.fl.OnUnknown(s.onUnknow)
+}
 
-	return s
+// envInt return int from environment variable or def
+func envInt(key string, def int) int {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	i, err := strconv.Atoi(v)
+	if err != nil {
+		log.WithError(err).Fatalf("Invalid %s", key)
+	}
+
+	return i
+}
+
+// envDuration return duration from environment variable or def
+func envDuration(key string, def time.Duration) time.Duration {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		log.WithError(err).Fatalf("Invalid %s", key)
+	}
+
+	return d
+}
+
+// Close wait queued events and close service
+func (s *Service) Close(ctx context.Context) error {
+	err := s.queue.Close(ctx)
+
+	s.stopOutbox()
+
+	if dedupErr := s.dedup.Close(); err == nil {
+		err = dedupErr
+	}
+
+	if storageErr := s.storage.Close(); err == nil {
+		err = storageErr
+	}
+
+	return err
+}
+
+// initDedup init store of delivered webhooks
+func (s *Service) initDedup() {
+	window := envDuration("GITLABVK_DEDUP_WINDOW", defaultDedupWindow)
+
+	path := os.Getenv("GITLABVK_DEDUP_FILE")
+	if path == "" {
+		path = defaultDedupFile
+	}
+
+	dedup, err := internal.NewDedup(window, path)
+	if err != nil {
+		log.WithError(err).Fatal("Dedup init error")
+	}
+
+	s.dedup = dedup
+}
+
+// initAdmin enable admin webhook for GitLab system hooks
+func (s *Service) initAdmin() {
+	adminToken := os.Getenv("GITLABVK_ADMIN_TOKEN")
+	if adminToken == "" {
+		return
+	}
+
+	peerID, err := strconv.Atoi(os.Getenv("GITLABVK_ADMIN_PEER_ID"))
+	if err != nil || peerID == 0 {
+		log.WithError(err).Fatal("GITLABVK_ADMIN_PEER_ID is required with GITLABVK_ADMIN_TOKEN")
+	}
+
+	s.adminPeerID = peerID
+	s.adminFl = gitlab.NewFuncList()
+	s.adminFl.Use(s.queueMiddleware, logMiddleware, gitlab.Recoverer)
+
+	s.adminFl.OnSystemHookProject(s.onSystemHookProject)
+	s.adminFl.OnSystemHookTeamMember(s.onSystemHookTeamMember)
+	s.adminFl.OnSystemHookUser(s.onSystemHookUser)
+	s.adminFl.OnSystemHookGroup(s.onSystemHookGroup)
+	s.adminFl.OnSystemHookGroupMember(s.onSystemHookGroupMember)
+	s.adminFl.OnSystemHookRepositoryUpdate(s.onSystemHookRepositoryUpdate)
+	s.adminFl.OnUnknown(s.onUnknow)
+
+	s.adminWebhook = gitlab.NewWebhookHandler(
+		s.adminFl,
+		gitlab.WithTokenVerifier(gitlab.StaticToken(adminToken)),
+		gitlab.WithMaxBodySize(maxContentLength),
+		gitlab.WithDeduplicator(s.dedup),
+		gitlab.WithErrorLog(webhookErrorLog),
+	)
 }
 
 // KeyboardBuild return main keyboard
-func (s *Service) KeyboardBuild() *object.MessagesKeyboard {
+func (s *Service) KeyboardBuild(peerID int) *object.MessagesKeyboard {
 	keyboard := object.NewMessagesKeyboard(true)
 	keyboard.AddRow().AddTextButton(
 		"Настройки для webhook",
@@ -126,81 +327,466 @@ func (s *Service) KeyboardBuild() *object.MessagesKeyboard {
 		"negative",
 	)
 
+	// labels are built even if storage is unavailable
+	confidentialLabel := "Скрывать конфиденциальное"
+	if enabled, _ := s.confidentialEnabled(peerID); !enabled {
+		confidentialLabel = "Показывать конфиденциальное"
+	}
+
+	keyboard.AddRow().AddTextButton(
+		confidentialLabel,
+		ButtonPayload{
+			Command: toggleConfidential,
+		}.String(),
+		"",
+	)
+
+	emojiLabel := "Включить ленту 👍"
+	if emoji, _ := s.emojiList(peerID); len(emoji) > 0 {
+		emojiLabel = "Выключить ленту 👍"
+	}
+
+	keyboard.AddRow().AddTextButton(
+		emojiLabel,
+		ButtonPayload{
+			Command: toggleEmoji,
+		}.String(),
+		"",
+	)
+	keyboard.AddRow().AddTextButton(
+		"Типы событий",
+		ButtonPayload{
+			Command: eventSettings,
+		}.String(),
+		"",
+	)
+	keyboard.AddRow().AddTextButton(
+		"Фильтр веток",
+		ButtonPayload{
+			Command: refFilters,
+		}.String(),
+		"",
+	).AddTextButton(
+		"Фильтр issue и MR",
+		ButtonPayload{
+			Command: issueFilters,
+		}.String(),
+		"",
+	)
+	keyboard.AddRow().AddTextButton(
+		"Webhooks",
+		ButtonPayload{
+			Command: hookList,
+		}.String(),
+		"",
+	)
+
+	if isChat(peerID) {
+		mentionsLabel := "Включить упоминания"
+		if enabled, _ := s.mentionsEnabled(peerID); enabled {
+			mentionsLabel = "Выключить упоминания"
+		}
+
+		keyboard.AddRow().AddTextButton(
+			mentionsLabel,
+			ButtonPayload{
+				Command: toggleMentions,
+			}.String(),
+			"",
+		)
+	}
+
 	return keyboard
 }
 
-func (s *Service) settingMessageBuild(userID int) (text string) {
-	u, err := url.Parse(s.domain)
+// settingMessageBuild return settings of peer webhook. URL and token are
+// added only if secret is true, because in chats they are visible to every
+// member.
+func (s *Service) settingMessageBuild(peerID int, hook string, secret bool) (text string, err error) {
+	confidential, err := s.confidentialEnabled(peerID)
 	if err != nil {
-		log.WithError(err).Fatal("Invalid domain")
+		return "", err
 	}
 
-	if u.Scheme == "" {
-		u.Scheme = "https"
+	emoji, err := s.emojiList(peerID)
+	if err != nil {
+		return "", err
+	}
+
+	if hook != "" {
+		h, _, err := s.findHook(peerID, hook)
+		if err != nil {
+			return "", err
+		}
+
+		text += "Webhook: " + h.Name + " (" + hook + ")\n"
 	}
 
-	u.Path += "/webhook/" + strconv.Itoa(userID)
+	if secret {
+		u, err := url.Parse(s.domain)
+		if err != nil {
+			log.WithError(err).Fatal("Invalid domain")
+		}
 
-	text += "URL: " + u.String() + "\n"
-	text += "Secret Token: " + s.generateToken(userID) + "\n"
+		if u.Scheme == "" {
+			u.Scheme = "https"
+		}
 
-	return
+		u.Path += "/webhook/" + strconv.Itoa(peerID)
+		if hook != "" {
+			u.Path += "/" + hook
+		}
+
+		token, err := s.generateToken(peerID, hook)
+		if err != nil {
+			return "", err
+		}
+
+		text += "URL: " + u.String() + "\n"
+		text += "Secret Token: " + token + "\n\n"
+	}
+
+	if confidential {
+		text += "Конфиденциальные issue и комментарии: показываются\n"
+	} else if isChat(peerID) {
+		text += "Конфиденциальные issue и комментарии: скрыты, в беседах их нужно включить\n"
+	} else {
+		text += "Конфиденциальные issue и комментарии: скрыты\n"
+	}
+
+	if len(emoji) > 0 {
+		text += "Эмодзи: " + strings.Join(emoji, ", ") + ", изменить: /emoji\n"
+	} else {
+		text += "Эмодзи: выключены\n"
+	}
+
+	if isChat(peerID) {
+		mentions, err := s.mentionsEnabled(peerID)
+		if err != nil {
+			return "", err
+		}
+
+		if mentions {
+			text += "Упоминания: включены\n"
+		} else {
+			text += "Упоминания: выключены\n"
+		}
+	}
+
+	events, err := s.eventsMessageBuild(peerID, hook)
+	if err != nil {
+		return "", err
+	}
+
+	text += events
+
+	filters, err := s.filtersMessageBuild(peerID, hook)
+	if err != nil {
+		return "", err
+	}
+
+	return text + filters, nil
+}
+
+// filtersMessageBuild return ref and issue filters of peer webhook
+func (s *Service) filtersMessageBuild(peerID int, hook string) (text string, err error) {
+	patterns, err := s.refPatterns(peerID, hook)
+	if err != nil {
+		return "", err
+	}
+
+	if len(patterns) > 0 {
+		text += "Фильтр веток: " + strings.Join(patterns, " ") + "\n"
+	} else {
+		text += "Фильтр веток: все ветки\n"
+	}
+
+	filter, err := s.issueFilter(peerID, hook)
+	if err != nil {
+		return "", err
+	}
+
+	if len(filter) > 0 {
+		text += "Фильтр issue и merge request: " + filter.String() + "\n"
+	} else {
+		text += "Фильтр issue и merge request: все\n"
+	}
+
+	return text, nil
 }
 
 // MessageNew callback handler
-func (s *Service) MessageNew(_ context.Context, obj events.MessageNewObject) {
-	if obj.Message.PeerID > 2e9 {
+func (s *Service) MessageNew(ctx context.Context, obj events.MessageNewObject) { // nolint:gocyclo,funlen
+	peerID := obj.Message.PeerID
+	fromID := obj.Message.FromID
+	chat := isChat(peerID)
+
+	if chat && obj.Message.Action.Type != "" {
+		s.chatAction(ctx, peerID, obj.Message.Action)
 		return
 	}
 
 	var p ButtonPayload
 	_ = json.Unmarshal([]byte(obj.Message.Payload), &p)
 
+	// in chats text commands are parsed only if bot is mentioned
+	if p.Command == "" && (!chat || botMentioned(ctx, obj.Message.Text)) {
+		p, _ = textCommand(obj.Message.Text)
+	}
+
+	// in chats bot answers only to buttons and mentions
+	if chat && p.Command == "" && !botMentioned(ctx, obj.Message.Text) {
+		return
+	}
+
+	// settings may be changed by another replica
+	s.invalidatePeer(peerID)
+
+	if chat && adminCommand(p.Command) {
+		if message, ok := s.checkChatAdmin(peerID, fromID); !ok {
+			s.reply(peerID, message, nil)
+			return
+		}
+	}
+
 	var (
-		message    string
-		attachment string
-		keyboard   *object.MessagesKeyboard
+		message  string
+		keyboard *object.MessagesKeyboard
+		secret   bool
+		err      error
 	)
 
-	keyboard = s.KeyboardBuild()
+	fields := log.Fields{
+		"user_id": fromID,
+		"peer_id": peerID,
+	}
+
+	if p.Hook != "" {
+		var found bool
+
+		// hook of text command may be mistyped or already removed
+		if validHookID(p.Hook) {
+			_, found, err = s.findHook(peerID, p.Hook)
+		}
+
+		switch {
+		case err != nil:
+			s.reply(peerID, "Настройки временно недоступны, попробуйте позже", nil)
+			return
+		case !found:
+			s.reply(peerID, "Webhook не найден", s.hooksKeyboardBuild(peerID))
+			return
+		}
+	}
 
 	switch p.Command {
 	case notSupportedButton:
-		log.WithFields(log.Fields{
-			"user_id": obj.Message.FromID,
-		}).Info("User not support button")
+		log.WithFields(fields).Info("User not support button")
 
 		message = "Ваш клиент не поддерживает эту кнопку"
 	case getSetting:
-		log.WithFields(log.Fields{
-			"user_id": obj.Message.FromID,
-		}).Info("User get setting")
+		log.WithFields(fields).WithField("hook", p.Hook).Info("User get setting")
 
-		message = "Ваши настройки для Webhooks\n\n"
-		message += s.settingMessageBuild(obj.Message.FromID)
+		message, err = s.settingMessage(peerID, p.Hook, "Ваши настройки для Webhooks\n\n", !chat)
+		secret = chat
 	case resetToken:
-		log.WithFields(log.Fields{
-			"user_id": obj.Message.FromID,
-		}).Info("User reset token")
-
-		_ = s.regenerateToken(obj.Message.FromID)
-		message = "Токен сброшен. Новые настройки для Webhooks:\n\n"
-		message += s.settingMessageBuild(obj.Message.FromID)
+		log.WithFields(fields).WithField("hook", p.Hook).Info("User reset token")
+
+		if _, err = s.regenerateToken(peerID, p.Hook); err == nil {
+			message, err = s.settingMessage(peerID, p.Hook, "Токен сброшен. Новые настройки для Webhooks:\n\n", !chat)
+		}
+
+		secret = chat
+	case toggleConfidential:
+		var enabled bool
+
+		enabled, err = s.confidentialEnabled(peerID)
+		if err != nil {
+			break
+		}
+
+		log.WithFields(fields).WithField("enabled", !enabled).Info("User toggle confidential")
+
+		if err = s.setConfidential(peerID, !enabled); err == nil {
+			message, err = s.settingMessage(peerID, "", "Настройки обновлены\n\n", !chat)
+		}
+	case toggleEmoji:
+		var emoji []string
+
+		emoji, err = s.emojiList(peerID)
+		if err != nil {
+			break
+		}
+
+		if len(emoji) == 0 {
+			emoji = []string{defaultEmoji}
+		} else {
+			emoji = nil
+		}
+
+		log.WithFields(fields).WithField("emoji", emoji).Info("User toggle emoji")
+
+		if err = s.setEmojiList(peerID, emoji); err == nil {
+			message, err = s.settingMessage(peerID, "", "Настройки обновлены\n\n", !chat)
+		}
+	case setEmoji:
+		message, err = s.emojiCommand(peerID, p.Payload, fields)
+	case eventSettings:
+		message, err = s.eventsMessageBuild(peerID, p.Hook)
+		keyboard = s.eventKeyboardBuild(peerID, p.Hook)
+	case toggleEvent:
+		if !isEventKind(gitlab.EventType(p.Payload)) {
+			message = "Неизвестный тип события"
+			keyboard = s.eventKeyboardBuild(peerID, p.Hook)
+
+			break
+		}
+
+		var enabled bool
+
+		enabled, err = s.toggleEventType(peerID, p.Hook, gitlab.EventType(p.Payload))
+		if err != nil {
+			break
+		}
+
+		log.WithFields(fields).WithFields(log.Fields{
+			"hook":    p.Hook,
+			"event":   p.Payload,
+			"enabled": enabled,
+		}).Info("User toggle event")
+
+		message, err = s.eventsMessageBuild(peerID, p.Hook)
+		keyboard = s.eventKeyboardBuild(peerID, p.Hook)
+	case refFilters:
+		if p.Payload != "" {
+			patterns := strings.Fields(p.Payload)
+			if p.Payload == clearRefPatterns {
+				patterns = nil
+			}
+
+			log.WithFields(fields).WithFields(log.Fields{
+				"hook":     p.Hook,
+				"patterns": patterns,
+			}).Info("User set ref filters")
+
+			err = s.setRefPatterns(peerID, p.Hook, patterns)
+			if errors.Is(err, errBadPattern) {
+				message = "Неверный шаблон: " + err.Error() + "\n\n"
+				err = nil
+			}
+		}
+
+		if err == nil {
+			var text string
+
+			text, err = s.refsMessageBuild(peerID, p.Hook)
+			message += text
+		}
+	case issueFilters:
+		if p.Payload != "" {
+			log.WithFields(fields).WithFields(log.Fields{
+				"hook":   p.Hook,
+				"filter": p.Payload,
+			}).Info("User set issue filter")
+
+			if p.Payload == clearIssueFilter {
+				err = s.setKey(peerID, hookKey(issueFilterKey, p.Hook), "")
+			} else {
+				err = s.setIssueFilter(peerID, p.Hook, p.Payload)
+			}
+
+			if errors.Is(err, errBadPattern) {
+				message = "Неверное условие: " + err.Error() + "\n\n"
+				err = nil
+			}
+		}
+
+		if err == nil {
+			var text string
+
+			text, err = s.issueFilterMessageBuild(peerID, p.Hook)
+			message += text
+		}
+	case hookList, hookMenu, createHook, renameHook, deleteHook, deleteHookConfirm:
+		message, keyboard, err = s.hookCommand(peerID, p, fields)
+	case linkAccount, unlinkAccount:
+		message, err = s.accountCommand(p, fromID, chat, fields)
+	case subscribeEvents, unsubscribeEvents:
+		message, keyboard, err = s.subscribeCommand(peerID, p, fields)
+	case showFilters:
+		message, err = s.filtersMessageBuild(peerID, p.Hook)
+		message += "\nИзменить: /refs и /filter"
+	case muteNotifications:
+		message, err = s.muteCommand(peerID, p.Payload, fields)
+	case showStatus:
+		message, err = s.statusMessageBuild(peerID)
+	case showHelp:
+		message = helpMessage(p.Payload)
+	case toggleMentions:
+		if !chat {
+			message = "Упоминания настраиваются в беседах"
+			break
+		}
+
+		var enabled bool
+
+		enabled, err = s.mentionsEnabled(peerID)
+		if err != nil {
+			break
+		}
+
+		log.WithFields(fields).WithField("enabled", !enabled).Info("User toggle mentions")
+
+		if err = s.setMentions(peerID, !enabled); err == nil {
+			message, err = s.settingMessage(peerID, "", "Настройки обновлены\n\n", !chat)
+		}
 	default:
-		message = "Ваши настройки для Webhooks\n\n"
-		message += s.settingMessageBuild(obj.Message.FromID)
+		message, err = s.settingMessage(peerID, "", "Ваши настройки для Webhooks\n\n", !chat)
+		secret = chat
+	}
+
+	if err == nil && secret {
+		var status string
+
+		status, err = s.sendChatSecret(peerID, fromID, p.Hook)
+		message += "\n" + status
+	}
+
+	if err != nil {
+		message = "Настройки временно недоступны, попробуйте позже"
+	}
+
+	if keyboard == nil {
+		keyboard = s.menuKeyboardBuild(peerID, p.Hook)
+	}
+
+	s.reply(peerID, message, keyboard)
+}
+
+// settingMessage return header with settings of peer webhook
+func (s *Service) settingMessage(peerID int, hook, header string, secret bool) (string, error) {
+	text, err := s.settingMessageBuild(peerID, hook, secret)
+	if err != nil {
+		return "", err
 	}
 
+	return header + text, nil
+}
+
+// reply send answer to message of user
+func (s *Service) reply(peerID int, message string, keyboard *object.MessagesKeyboard) {
 	params := api.Params{
-		"peer_id":          obj.Message.PeerID,
+		"peer_id":          peerID,
 		"random_id":        0,
 		"message":          message,
-		"attachment":       attachment,
-		"keyboard":         keyboard,
 		"dont_parse_links": true,
 		"disable_mentions": true,
 	}
 
+	if keyboard != nil {
+		params["keyboard"] = keyboard
+	}
+
 	_, err := s.vk.MessagesSend(params)
 	if err != nil {
 		log.WithError(err).WithFields(log.Fields(params)).Error("Message send error")
@@ -209,10 +795,6 @@ func (s *Service) MessageNew(_ context.Context, obj events.MessageNewObject) {
 
 // Webhook http handler
 func (s *Service) Webhook(w http.ResponseWriter, r *http.Request) {
-	// Get var from header
-	event := gitlab.EventType(r.Header.Get(gitlab.HeaderEvent))
-	token := r.Header.Get(gitlab.HeaderToken)
-
 	userID, err := strconv.Atoi(mux.Vars(r)["id"])
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
@@ -221,59 +803,94 @@ func (s *Service) Webhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	logField := log.Fields{
-		"userID":        userID,
-		"ip":            internal.GetIP(r),
-		"ContentLength": r.ContentLength,
-		"event":         event,
-	}
+	hook := mux.Vars(r)["hook"]
+	if hook != "" {
+		status := http.StatusNotFound
 
-	// Check token
-	if !s.checkToken(token, userID) {
-		log.WithFields(logField).Info("Forbidden")
-		w.WriteHeader(http.StatusForbidden)
-		_, _ = w.Write([]byte(http.StatusText(http.StatusForbidden)))
+		var ok bool
+		if validHookID(hook) {
+			_, ok, err = s.findHook(userID, hook)
+			if err != nil {
+				status = http.StatusServiceUnavailable
+			}
+		}
 
-		return
+		if err != nil || !ok {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(http.StatusText(status)))
+
+			return
+		}
 	}
 
-	// Check Content Length
-	if r.ContentLength > maxContentLength {
-		log.WithFields(logField).Info("Request Entity Too Large")
-		w.WriteHeader(http.StatusRequestEntityTooLarge)
-		_, _ = w.Write([]byte(http.StatusText(http.StatusRequestEntityTooLarge)))
+	// Token can't be verified without salt. Storage outage is reported as
+	// 5xx, so GitLab doesn't treat it as invalid token and disable webhook.
+	// Peer without salt never got settings, so it has no webhook.
+	salt, err := s.webhookSalt(userID, hook)
+	if err != nil || salt == "" {
+		status := http.StatusNotFound
+		if err != nil {
+			s.metrics.Add("webhook_storage_errors", 1)
 
-		return
-	}
+			status = http.StatusServiceUnavailable
+		}
+
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(http.StatusText(status)))
 
-	// Get data
-	data, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		log.WithFields(logField).WithError(err).Info("Read body error")
-		// NOTE: 499?
 		return
 	}
 
-	log.Trace(string(data))
+	if isChat(userID) {
+		disabled, err := s.chatDisabled(userID)
+		if err != nil || disabled {
+			status := http.StatusGone
+			if err != nil {
+				status = http.StatusServiceUnavailable
+			}
 
-	// Handler event
-	ctx := context.Background()
-	ctx = context.WithValue(ctx, contextUserID, userID)
-	ctx = context.WithValue(ctx, contextEventType, event)
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(http.StatusText(status)))
 
-	err = s.fl.Handler(ctx, event, data)
-	if err != nil {
-		log.WithFields(logField).WithError(err).Error("Handler event error")
-		w.WriteHeader(http.StatusBadRequest)
-		_, _ = w.Write([]byte(http.StatusText(http.StatusBadRequest)))
-
-		return
+			return
+		}
 	}
 
-	// return ok
-	log.WithFields(logField).Info("ok")
+	ctx := context.WithValue(r.Context(), contextUserID, userID)
+	ctx = context.WithValue(ctx, contextHookID, hook)
+	ctx = context.WithValue(ctx, contextSalt, salt)
+	s.webhook.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// AdminWebhook http handler for GitLab system hooks
+func (s *Service) AdminWebhook(w http.ResponseWriter, r *http.Request) {
+	ctx := context.WithValue(r.Context(), contextUserID, s.adminPeerID)
+	s.adminWebhook.ServeHTTP(w, r.WithContext(ctx))
+}
+
+// verifyToken check webhook token of user. Salt is already read by Webhook.
+func (s *Service) verifyToken(r *http.Request, token string) bool {
+	ctx := r.Context()
+
+	return s.checkToken(token, getUserID(ctx), getHookID(ctx), getSalt(ctx))
+}
 
-	_, _ = w.Write([]byte(http.StatusText(http.StatusOK)))
+// webhookErrorLog log failed webhook request
+func webhookErrorLog(r *http.Request, status int, err error) {
+	entry := log.WithFields(log.Fields{
+		"userID":        getUserID(r.Context()),
+		"hook":          getHookID(r.Context()),
+		"ip":            internal.GetIP(r),
+		"ContentLength": r.ContentLength,
+		"event":         r.Header.Get(gitlab.HeaderEvent),
+		"status":        status,
+	}).WithError(err)
+
+	if status >= http.StatusInternalServerError {
+		entry.Error("Handler event error")
+	} else {
+		entry.Info(http.StatusText(status))
+	}
 }
 
 // CallbackUpdate update callback setting
@@ -302,7 +919,8 @@ func (s *Service) Callback(w http.ResponseWriter, r *http.Request) {
 	s.cb.HandleFunc(w, r)
 }
 
-func init() {
+// initLog parse flags and set logger level
+func initLog() {
 	// Flags
 	lvl := flag.String("level", "info", "logger level")
 	flag.Parse()
@@ -319,7 +937,31 @@ func init() {
 	log.SetLevel(level)
 }
 
+// router return routes of service
+func (s *Service) router() *mux.Router {
+	r := mux.NewRouter()
+	r.HandleFunc("/webhook/{id}", s.Webhook)
+	r.HandleFunc("/webhook/{id}/{hook}", s.Webhook)
+	r.HandleFunc("/callback", s.Callback)
+
+	if s.adminFl != nil {
+		r.HandleFunc("/admin/webhook", s.AdminWebhook)
+	}
+
+	return r
+}
+
 func main() {
+	initLog()
+
+	if flag.Arg(0) == "outbox" {
+		if err := runOutboxCommand(flag.Args()[1:]); err != nil {
+			log.WithError(err).Fatal("Outbox command error")
+		}
+
+		return
+	}
+
 	s := NewService(os.Getenv("GITLABVK_DOMAIN"))
 
 	addr := os.Getenv("GITLABVK_ADDR")
@@ -327,17 +969,46 @@ func main() {
 		addr = ":8080"
 	}
 
-	// Router setting
-	r := mux.NewRouter()
-	r.HandleFunc("/webhook/{id}", s.Webhook)
-	r.HandleFunc("/callback", s.Callback)
-	http.Handle("/", r)
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: s.router(),
+	}
+
+	if metricsAddr := os.Getenv("GITLABVK_METRICS_ADDR"); metricsAddr != "" {
+		go func() {
+			log.Printf("Start metrics server on %s", metricsAddr)
+
+			if err := http.ListenAndServe(metricsAddr, expvar.Handler()); err != nil {
+				log.WithError(err).Error("Metrics server error")
+			}
+		}()
+	}
+
 	log.Printf("Start server on %s", addr)
 
 	// паралельно обновляем callback
 	go s.CallbackUpdate()
 
-	if err := http.ListenAndServe(addr, nil); err != nil {
-		log.WithError(err).Fatal("ListenAndServe error")
+	go func() {
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.WithError(err).Fatal("ListenAndServe error")
+		}
+	}()
+
+	stop := make(chan os.Signal, 1)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+	<-stop
+
+	log.Info("Shutdown server")
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	if err := srv.Shutdown(ctx); err != nil {
+		log.WithError(err).Error("Shutdown error")
+	}
+
+	if err := s.Close(ctx); err != nil {
+		log.WithError(err).Error("Queue drain error")
 	}
 }
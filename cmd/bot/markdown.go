@@ -0,0 +1,78 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+const (
+	maxDescriptionLength = 1000
+
+	// maxButtonLabel is limit of VK button label
+	maxButtonLabel = 40
+)
+
+var (
+	mdImage   = regexp.MustCompile(`!\[([^\]]*)\]\(([^)]*)\)`)
+	mdLink    = regexp.MustCompile(`\[([^\]]+)\]\(([^)]*)\)`)
+	mdHeading = regexp.MustCompile(`(?m)^\s{0,3}#{1,6}\s*`)
+	mdBold    = regexp.MustCompile(`\*\*([^*\n]+?)\*\*`)
+	mdStrike  = regexp.MustCompile(`~~([^~\n]+?)~~`)
+	// underscores are emphasis only around words separated by space, so
+	// names like __init__ or snake__case are kept
+	mdUnderscore = regexp.MustCompile(`(^|[\s(])__([^\s_][^_\n]*?\s[^_\n]*?[^\s_])__($|[\s).,!?:;])`)
+	mdInlineCode = regexp.MustCompile("`([^`]+)`")
+	mdListItem   = regexp.MustCompile(`(?m)^(\s*)[*+-]\s+`)
+	mdQuote      = regexp.MustCompile(`(?m)^\s*>\s?`)
+	mdBlankLines = regexp.MustCompile(`\n{3,}`)
+)
+
+// markdownToText convert GitLab markdown to plain text for VK message
+func markdownToText(md string) string {
+	md = strings.ReplaceAll(md, "\r\n", "\n")
+
+	lines := strings.Split(md, "\n")
+	text := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), "```") {
+			continue
+		}
+
+		text = append(text, line)
+	}
+
+	md = strings.Join(text, "\n")
+	md = mdImage.ReplaceAllString(md, "$1")
+	md = mdLink.ReplaceAllString(md, "$1 ($2)")
+	md = mdHeading.ReplaceAllString(md, "")
+	md = mdBold.ReplaceAllString(md, "$1")
+	md = mdStrike.ReplaceAllString(md, "$1")
+	md = mdUnderscore.ReplaceAllString(md, "$1$2$3")
+	md = mdInlineCode.ReplaceAllString(md, "$1")
+	md = mdListItem.ReplaceAllString(md, "$1• ")
+	md = mdQuote.ReplaceAllString(md, "")
+	md = mdBlankLines.ReplaceAllString(md, "\n\n")
+
+	return strings.TrimSpace(md)
+}
+
+// trimText trim text to n runes
+func trimText(text string, n int) string {
+	r := []rune(text)
+	if len(r) <= n {
+		return text
+	}
+
+	return strings.TrimSpace(string(r[:n])) + "…"
+}
+
+// buttonLabel trim label to length allowed by VK
+func buttonLabel(label string) string {
+	label = strings.Join(strings.Fields(label), " ")
+	if len([]rune(label)) <= maxButtonLabel {
+		return label
+	}
+
+	return trimText(label, maxButtonLabel-1)
+}
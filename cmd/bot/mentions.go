@@ -0,0 +1,502 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SevereCloud/gitlabvk/pkg/gitlab"
+	"github.com/SevereCloud/vksdk/v2/object"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	linkKeyPrefix = "link_"
+
+	// claimCodePrefix is written before verification code, so code is not
+	// found in random text of comment
+	claimCodePrefix = "gitlabvk-"
+	claimCodeLength = 10
+	claimTTL        = time.Hour
+	maxClaimCodes   = 3
+
+	maxAccountLinks = 20
+	maxMentions     = 10
+	maxAccountName  = 255
+)
+
+// account errors
+var (
+	errAccountTaken = errors.New("account linked to another user")
+	errAccountLimit = errors.New("too many linked accounts")
+	errAccountName  = errors.New("bad account name")
+)
+
+var (
+	gitlabUsernameRe = regexp.MustCompile(`^[a-z0-9_][a-z0-9_.\-]*$`)
+	noteMentionRe    = regexp.MustCompile(`(?:^|[^\w.\-/@])@([a-zA-Z0-9_][a-zA-Z0-9_.\-]*[a-zA-Z0-9_]|[a-zA-Z0-9_])`)
+	vkLinkMentionRe  = regexp.MustCompile(`(?i)\[(id|club|public)`)
+	vkAtMentionRe    = regexp.MustCompile(`([@*])([\pL\d_])`)
+	claimCodeRe      = regexp.MustCompile(claimCodePrefix + `(\d+)-[0-9A-Za-z]{` + strconv.Itoa(claimCodeLength) + `}`)
+)
+
+// account is GitLab user, which can be mentioned
+type account struct {
+	Username string
+}
+
+// accountLink is GitLab account, which VK user linked in chat
+type accountLink struct {
+	PeerID int
+	Name   string
+}
+
+// parseAccountLinks parse links like "2000000001/username"
+func parseAccountLinks(value string) []accountLink {
+	var links []accountLink
+
+	for _, field := range strings.Fields(value) {
+		i := strings.Index(field, "/")
+		if i < 0 {
+			continue
+		}
+
+		peerID, err := strconv.Atoi(field[:i])
+		if err != nil {
+			continue
+		}
+
+		links = append(links, accountLink{PeerID: peerID, Name: field[i+1:]})
+	}
+
+	return links
+}
+
+// formatAccountLinks return value of accountLinksKey
+func formatAccountLinks(links []accountLink) string {
+	fields := make([]string, len(links))
+
+	for i, l := range links {
+		fields[i] = strconv.Itoa(l.PeerID) + "/" + l.Name
+	}
+
+	return strings.Join(fields, " ")
+}
+
+// normalizeAccount return username without @ in lower case. Emails are not
+// supported, because GitLab hides them in webhooks.
+func normalizeAccount(name string) (string, error) {
+	name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "@"))
+
+	if name == "" || len(name) > maxAccountName || !gitlabUsernameRe.MatchString(name) {
+		return "", errAccountName
+	}
+
+	return name, nil
+}
+
+// accountKey return registry key of account. Usernames contain dots, which
+// are not allowed in VK storage keys, so hash is used.
+func accountKey(name string) string {
+	sum := sha256.Sum256([]byte(name))
+
+	return linkKeyPrefix + hex.EncodeToString(sum[:16])
+}
+
+// accountLinks return GitLab accounts linked by VK user
+func (s *Service) accountLinks(vkID int) ([]accountLink, error) {
+	value, err := s.getKey(vkID, accountLinksKey)
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAccountLinks(value), nil
+}
+
+// addAccountClaim return code, which user must write in GitLab comment from
+// the account to prove ownership. Code contains VK id, so claim is found
+// without global registry. New claim replaces previous claim of user.
+func (s *Service) addAccountClaim(vkID int, name string) (string, error) {
+	name, err := normalizeAccount(name)
+	if err != nil {
+		return "", err
+	}
+
+	links, err := s.accountLinks(vkID)
+	if err != nil {
+		return "", err
+	}
+
+	if len(links) >= maxAccountLinks {
+		return "", errAccountLimit
+	}
+
+	code := claimCodePrefix + strconv.Itoa(vkID) + "-" + GenerateRandomString(claimCodeLength)
+	value := fmt.Sprintf("%s %s %d", code, name, time.Now().Add(claimTTL).Unix())
+
+	if err := s.setKey(vkID, accountClaimKey, value); err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// verifyAccount link account of comment author in chat, if comment has code
+// of claim of this account and user of claim is member of chat. Anyone with
+// token of webhook can send fake comment, so link is valid only in the
+// chat of webhook. Code can be used in several chats until it expires.
+func (s *Service) verifyAccount(peerID int, user gitlab.User, note string) {
+	if !isChat(peerID) {
+		return
+	}
+
+	for _, m := range claimCodeRe.FindAllStringSubmatch(note, maxClaimCodes) {
+		vkID, err := strconv.Atoi(m[1])
+		if err != nil {
+			continue
+		}
+
+		value, err := s.getKey(vkID, accountClaimKey)
+		if err != nil || value == "" {
+			continue
+		}
+
+		var (
+			code, name string
+			expires    int64
+		)
+
+		_, err = fmt.Sscanf(value, "%s %s %d", &code, &name, &expires)
+		if err != nil || code != m[0] || time.Now().Unix() > expires || !strings.EqualFold(name, user.Username) {
+			continue
+		}
+
+		if s.vkUserOf(peerID, account{Username: name}) == vkID {
+			continue
+		}
+
+		fields := log.Fields{
+			"peer_id": peerID,
+			"userID":  vkID,
+			"account": name,
+		}
+
+		if member, err := s.chatMember(peerID, vkID); err != nil || !member {
+			log.WithFields(fields).Warn("Account claimed by user outside of chat")
+			continue
+		}
+
+		err = s.addAccountLink(peerID, vkID, name)
+
+		switch {
+		case err == nil:
+			log.WithFields(fields).Info("User verified account")
+
+			s.sendMessage(vkID, "Аккаунт GitLab "+name+" привязан в беседе "+strconv.Itoa(peerID-chatPeerOffset), nil)
+		case errors.Is(err, errAccountTaken):
+			s.sendMessage(vkID, "Аккаунт GitLab "+name+" уже привязан другим участником беседы. "+
+				"Сначала участник должен отвязать его командой /unlink "+name, nil)
+		case errors.Is(err, errAccountLimit):
+			s.sendMessage(vkID, "Можно привязать не больше "+strconv.Itoa(maxAccountLinks)+" аккаунтов", nil)
+		default:
+			log.WithFields(fields).WithError(err).Warn("Account not linked")
+		}
+	}
+}
+
+// verifyMiddleware verify accounts by comments. It is called before
+// filters, so comments of muted peers and peers without comment events
+// verify accounts too.
+func (s *Service) verifyMiddleware(next gitlab.HandlerFunc) gitlab.HandlerFunc {
+	return func(ctx context.Context, e gitlab.Event) error {
+		if note, ok := e.Value.(*gitlab.EventNote); ok {
+			s.verifyAccount(getUserID(ctx), note.User, note.ObjectAttributes.Note)
+		}
+
+		return next(ctx, e)
+	}
+}
+
+// addAccountLink link verified GitLab username to VK user in chat. Account
+// linked by another user must be unlinked by that user first. Registry key
+// of chat is locked before key of user, so concurrent links of account
+// can't both win.
+func (s *Service) addAccountLink(peerID, vkID int, name string) error {
+	name, err := normalizeAccount(name)
+	if err != nil {
+		return err
+	}
+
+	link := accountLink{PeerID: peerID, Name: name}
+
+	return s.updateKey(peerID, accountKey(name), func(owner string) (string, error) {
+		if owner != "" && owner != strconv.Itoa(vkID) {
+			return "", errAccountTaken
+		}
+
+		err := s.updateKey(vkID, accountLinksKey, func(old string) (string, error) {
+			links := parseAccountLinks(old)
+
+			for _, l := range links {
+				if l == link {
+					return old, nil
+				}
+			}
+
+			if len(links) >= maxAccountLinks {
+				return "", errAccountLimit
+			}
+
+			return formatAccountLinks(append(links, link)), nil
+		})
+
+		return strconv.Itoa(vkID), err
+	})
+}
+
+// removeAccountLink unlink GitLab account from VK user in all chats. Empty
+// name unlinks all accounts.
+func (s *Service) removeAccountLink(vkID int, name string) error {
+	if name != "" {
+		var err error
+		if name, err = normalizeAccount(name); err != nil {
+			return err
+		}
+	}
+
+	var removed []accountLink
+
+	err := s.updateKey(vkID, accountLinksKey, func(old string) (string, error) {
+		var kept []accountLink
+
+		removed = nil
+
+		for _, link := range parseAccountLinks(old) {
+			if name != "" && link.Name != name {
+				kept = append(kept, link)
+			} else {
+				removed = append(removed, link)
+			}
+		}
+
+		return formatAccountLinks(kept), nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// registry is updated after user key is unlocked, so locks are never
+	// taken in order opposite to addAccountLink
+	for _, link := range removed {
+		err := s.updateKey(link.PeerID, accountKey(link.Name), func(owner string) (string, error) {
+			if owner != strconv.Itoa(vkID) {
+				return owner, nil
+			}
+
+			return "", nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// vkUserOf return VK user linked with account in chat or 0
+func (s *Service) vkUserOf(peerID int, a account) int {
+	name, err := normalizeAccount(a.Username)
+	if err != nil {
+		return 0
+	}
+
+	value, err := s.getKey(peerID, accountKey(name))
+	if err != nil || value == "" {
+		return 0
+	}
+
+	id, _ := strconv.Atoi(value)
+
+	return id
+}
+
+// noteAccounts return users mentioned in note like @username
+func noteAccounts(note string) []account {
+	var accounts []account
+
+	for _, m := range noteMentionRe.FindAllStringSubmatch(note, -1) {
+		accounts = append(accounts, account{Username: m[1]})
+	}
+
+	return accounts
+}
+
+// assigneeAccounts return accounts of merge request assignees and reviewers
+func assigneeAccounts(assignees ...[]gitlab.MergeAssignee) []account {
+	var accounts []account
+
+	for _, list := range assignees {
+		for _, a := range list {
+			accounts = append(accounts, account{Username: a.Username})
+		}
+	}
+
+	return accounts
+}
+
+// escapeMentions break VK mentions in text from GitLab with zero width
+// space, so only mentions added by bot notify users
+func escapeMentions(text string) string {
+	text = vkLinkMentionRe.ReplaceAllString(text, "[\u200b$1")
+
+	return vkAtMentionRe.ReplaceAllString(text, "$1\u200b$2")
+}
+
+// mentionText return line with mentions of linked VK users. Actor of event
+// is not mentioned.
+func (s *Service) mentionText(peerID int, actor string, accounts []account) string {
+	if enabled, err := s.mentionsEnabled(peerID); err != nil || !enabled {
+		return ""
+	}
+
+	var (
+		mentions []string
+		seen     = make(map[int]bool)
+	)
+
+	actorID := 0
+	if actor != "" {
+		actorID = s.vkUserOf(peerID, account{Username: actor})
+	}
+
+	for _, a := range accounts {
+		if len(mentions) >= maxMentions {
+			break
+		}
+
+		if a.Username != "" && strings.EqualFold(a.Username, actor) {
+			continue
+		}
+
+		id := s.vkUserOf(peerID, a)
+		if id == 0 || id == actorID || seen[id] {
+			continue
+		}
+
+		seen[id] = true
+
+		name := a.Username
+		if name == "" {
+			name = "user"
+		}
+
+		mentions = append(mentions, "[id"+strconv.Itoa(id)+"|@"+name+"]")
+	}
+
+	if len(mentions) == 0 {
+		return ""
+	}
+
+	return "\n\n🔔 " + strings.Join(mentions, " ")
+}
+
+// sendMentionMessage send message with mentions of linked users, if chat
+// allows mentions
+func (s *Service) sendMentionMessage(
+	peerID int,
+	message string,
+	keyboard *object.MessagesKeyboard,
+	actor string,
+	accounts []account,
+) int {
+	text := s.mentionText(peerID, actor, accounts)
+	if text == "" {
+		return s.sendMessage(peerID, message, keyboard)
+	}
+
+	return s.sendMessageMentions(peerID, escapeMentions(message)+text, keyboard, true)
+}
+
+// accountLinksMessageBuild return GitLab accounts of VK user with help
+func (s *Service) accountLinksMessageBuild(vkID int) (string, error) {
+	links, err := s.accountLinks(vkID)
+	if err != nil {
+		return "", err
+	}
+
+	text := "Привязанные аккаунты GitLab: "
+	if len(links) == 0 {
+		text += "нет\n"
+	} else {
+		names := make([]string, len(links))
+		for i, l := range links {
+			names[i] = l.Name + " (беседа " + strconv.Itoa(l.PeerID-chatPeerOffset) + ")"
+		}
+
+		text += strings.Join(names, ", ") + "\n"
+	}
+
+	text += "\nБот упомянет вас в беседах, когда вас назначат на issue или merge request " +
+		"или упомянут в комментарии.\n" +
+		"Привязать: /link username, бот попросит подтвердить аккаунт " +
+		"комментарием в GitLab\n" +
+		"Отвязать: /unlink username, все аккаунты: /unlink"
+
+	return text, nil
+}
+
+// accountCommand handle linking of GitLab accounts
+func (s *Service) accountCommand(p ButtonPayload, fromID int, chat bool, fields log.Fields) (string, error) {
+	// verification code should not be visible to chat
+	if chat {
+		return "Привязать аккаунт GitLab можно в личных сообщениях сообщества", nil
+	}
+
+	var (
+		message string
+		err     error
+	)
+
+	switch {
+	case p.Command == linkAccount && p.Payload != "":
+		log.WithFields(fields).Info("User claim account")
+
+		var code string
+		if code, err = s.addAccountClaim(fromID, p.Payload); err == nil {
+			return fmt.Sprintf(
+				"Чтобы подтвердить, что аккаунт ваш, в течение часа напишите от него "+
+					"комментарий с кодом %s в issue или merge request проекта, события "+
+					"которого приходят в беседу. Вы должны быть участником беседы. "+
+					"Аккаунт будет привязан только в этой беседе.",
+				code,
+			), nil
+		}
+	case p.Command == unlinkAccount:
+		log.WithFields(fields).Info("User unlink account")
+
+		err = s.removeAccountLink(fromID, p.Payload)
+	}
+
+	switch {
+	case errors.Is(err, errAccountName):
+		message = "Укажите username аккаунта GitLab\n\n"
+		err = nil
+	case errors.Is(err, errAccountLimit):
+		message = "Можно привязать не больше " + strconv.Itoa(maxAccountLinks) + " аккаунтов\n\n"
+		err = nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	text, err := s.accountLinksMessageBuild(fromID)
+
+	return message + text, err
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/SevereCloud/gitlabvk/internal"
+	"github.com/SevereCloud/gitlabvk/pkg/gitlab"
+	log "github.com/sirupsen/logrus"
+)
+
+// queueMiddleware push event to queue and return immediately. Events of the
+// same peer are handled in order, one at a time.
+func (s *Service) queueMiddleware(next gitlab.HandlerFunc) gitlab.HandlerFunc {
+	return func(ctx context.Context, e gitlab.Event) error {
+		ctx = detach(ctx)
+		userID := getUserID(ctx)
+
+		err := s.queue.Push(userID, func() {
+			if err := next(ctx, e); err != nil {
+				log.WithFields(log.Fields{
+					"userID": userID,
+					"event":  e.Type,
+				}).WithError(err).Error("Handler event error")
+			}
+		})
+
+		switch {
+		case errors.Is(err, internal.ErrQueueFull):
+			return &gitlab.HTTPError{Code: http.StatusTooManyRequests, Err: err}
+		case errors.Is(err, internal.ErrQueueClosed):
+			return &gitlab.HTTPError{Code: http.StatusServiceUnavailable, Err: err}
+		}
+
+		return err
+	}
+}
+
+// logMiddleware log handled events
+func logMiddleware(next gitlab.HandlerFunc) gitlab.HandlerFunc {
+	return func(ctx context.Context, e gitlab.Event) error {
+		start := time.Now()
+
+		err := next(ctx, e)
+		if err == nil {
+			log.WithFields(log.Fields{
+				"userID":   getUserID(ctx),
+				"event":    e.Type,
+				"duration": time.Since(start),
+			}).Info("ok")
+		}
+
+		return err
+	}
+}
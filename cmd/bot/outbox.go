@@ -0,0 +1,272 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/SevereCloud/gitlabvk/internal"
+	"github.com/SevereCloud/vksdk/v2/api"
+	"github.com/SevereCloud/vksdk/v2/api/params"
+	"github.com/SevereCloud/vksdk/v2/object"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	defaultOutboxDir = "gitlabvk_outbox"
+
+	outboxInterval    = 10 * time.Second
+	outboxBaseBackoff = 30 * time.Second
+	outboxMaxBackoff  = time.Hour
+	outboxMaxAttempts = 10
+
+	// dead messages are kept for replay, but not forever
+	outboxTrimInterval = time.Hour
+	outboxDeadTTL      = 7 * day
+	outboxMaxDead      = 1000
+)
+
+// outboxDir return outbox directory from environment
+func outboxDir() string {
+	dir := os.Getenv("GITLABVK_OUTBOX_DIR")
+	if dir == "" {
+		dir = defaultOutboxDir
+	}
+
+	return dir
+}
+
+// outboxBackoff return delay before next attempt
+func outboxBackoff(attempts int) time.Duration {
+	d := outboxBaseBackoff
+
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= outboxMaxBackoff {
+			return outboxMaxBackoff
+		}
+	}
+
+	return d
+}
+
+// vkErrorCode return code of VK API error or ErrNoType for other errors
+func vkErrorCode(err error) api.ErrorType {
+	var e *api.Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+
+	return api.ErrNoType
+}
+
+// chatGone return true if error means that bot can't write to chat anymore
+func chatGone(errCode api.ErrorType) bool {
+	switch errCode {
+	case api.ErrMessagesChatUserNoAccess, api.ErrMessagesChatNotExist, api.ErrMessagesChatDisabled:
+		return true
+	default:
+		return false
+	}
+}
+
+// saveUndelivered save message to outbox. Permanent failed messages are
+// saved to dead list.
+func (s *Service) saveUndelivered(
+	peerID int,
+	message string,
+	keyboard *object.MessagesKeyboard,
+	mentions bool,
+	sendErr error,
+	permanent bool,
+) {
+	m := internal.OutboxMessage{
+		PeerID:      peerID,
+		Message:     message,
+		Mentions:    mentions,
+		Attempts:    1,
+		NextAttempt: time.Now().Add(outboxBackoff(1)),
+		CreatedAt:   time.Now(),
+	}
+
+	if keyboard != nil {
+		m.Keyboard = keyboard.ToJSON()
+	}
+
+	if sendErr != nil {
+		m.LastError = sendErr.Error()
+	}
+
+	var err error
+
+	if permanent {
+		err = s.outbox.AddDead(m)
+	} else {
+		err = s.outbox.Add(m)
+	}
+
+	if err != nil {
+		log.WithError(err).WithField("peer_id", peerID).Error("Outbox save error")
+	}
+}
+
+// runOutbox retry pending messages and remove old dead messages until ctx
+// is done
+func (s *Service) runOutbox(ctx context.Context) {
+	ticker := time.NewTicker(outboxInterval)
+	defer ticker.Stop()
+
+	trim := time.NewTicker(outboxTrimInterval)
+	defer trim.Stop()
+
+	s.trimOutbox()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.retryOutbox()
+		case <-trim.C:
+			s.trimOutbox()
+		}
+	}
+}
+
+// trimOutbox remove old dead messages
+func (s *Service) trimOutbox() {
+	n, err := s.outbox.TrimDead(outboxDeadTTL, outboxMaxDead)
+	if err != nil {
+		log.WithError(err).Error("Outbox trim error")
+	}
+
+	if n > 0 {
+		log.WithField("count", n).Info("Old dead outbox messages removed")
+	}
+}
+
+func (s *Service) retryOutbox() {
+	pending, err := s.outbox.Pending()
+	if err != nil {
+		log.WithError(err).Error("Outbox read error")
+		return
+	}
+
+	now := time.Now()
+
+	for _, m := range pending {
+		if m.NextAttempt.After(now) {
+			continue
+		}
+
+		// nobody will read messages of chat, which bot was kicked from
+		if disabled, err := s.chatDisabled(m.PeerID); err == nil && disabled {
+			log.WithField("id", m.ID).Info("Outbox message of disabled chat dropped")
+
+			if err := s.outbox.Remove(m.ID); err != nil {
+				log.WithError(err).Error("Outbox remove error")
+			}
+
+			continue
+		}
+
+		b := params.NewMessagesSendBuilder()
+		b.PeerID(m.PeerID)
+		b.RandomID(0)
+		b.Message(m.Message)
+		b.DisableMentions(!m.Mentions)
+		b.DontParseLinks(true)
+
+		if m.Keyboard != "" {
+			b.Keyboard(m.Keyboard)
+		}
+
+		_, err := s.vk.MessagesSend(b.Params)
+		if err == nil {
+			log.WithField("id", m.ID).Info("Outbox message delivered")
+
+			if err := s.outbox.Remove(m.ID); err != nil {
+				log.WithError(err).Error("Outbox remove error")
+			}
+
+			continue
+		}
+
+		m.Attempts++
+		m.LastError = err.Error()
+		m.NextAttempt = now.Add(outboxBackoff(m.Attempts))
+
+		errCode := vkErrorCode(err)
+		permanent := errCode != api.ErrNoType && errCode != api.ErrTooMany && errCode != api.ErrServer
+
+		if chatGone(errCode) {
+			s.disableChat(m.PeerID)
+			err = s.outbox.Remove(m.ID)
+		} else if permanent || m.Attempts >= outboxMaxAttempts {
+			log.WithError(err).WithField("id", m.ID).Warn("Outbox message is dead")
+			err = s.outbox.Kill(m)
+		} else {
+			err = s.outbox.Update(m)
+		}
+
+		if err != nil {
+			log.WithError(err).Error("Outbox update error")
+		}
+	}
+}
+
+// runOutboxCommand run CLI subcommand:
+//
+//	outbox list
+//	outbox dead
+//	outbox replay [id]
+func runOutboxCommand(args []string) error {
+	outbox, err := internal.NewOutbox(outboxDir())
+	if err != nil {
+		return err
+	}
+
+	if len(args) == 0 {
+		return fmt.Errorf("usage: outbox list|dead|replay [id]")
+	}
+
+	switch args[0] {
+	case "list", "dead":
+		list := outbox.Pending
+		if args[0] == "dead" {
+			list = outbox.Dead
+		}
+
+		messages, err := list()
+		if err != nil {
+			return err
+		}
+
+		for _, m := range messages {
+			fmt.Printf(
+				"%s\tpeer=%d\tattempts=%d\tcreated=%s\terror=%s\n\t%q\n",
+				m.ID, m.PeerID, m.Attempts,
+				m.CreatedAt.Format(time.RFC3339), m.LastError,
+				m.Message,
+			)
+		}
+	case "replay":
+		id := ""
+		if len(args) > 1 {
+			id = args[1]
+		}
+
+		n, err := outbox.Replay(id)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%d messages moved to outbox\n", n)
+	default:
+		return fmt.Errorf("unknown outbox command %s", args[0])
+	}
+
+	return nil
+}
@@ -0,0 +1,150 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/SevereCloud/gitlabvk/internal"
+	"github.com/SevereCloud/gitlabvk/pkg/gitlab"
+	"github.com/SevereCloud/vksdk/v2/api"
+)
+
+// outboxLen return number of pending and dead messages
+func outboxLen(t *testing.T, s *Service) (pending, dead int) {
+	t.Helper()
+
+	p, err := s.outbox.Pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := s.outbox.Dead()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return len(p), len(d)
+}
+
+// addPending add outbox message, which must be retried now
+func addPending(t *testing.T, s *Service, peerID int) {
+	t.Helper()
+
+	err := s.outbox.Add(internal.OutboxMessage{
+		PeerID:      peerID,
+		Message:     "message",
+		Attempts:    1,
+		NextAttempt: time.Now().Add(-time.Second),
+		CreatedAt:   time.Now(),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSendMessage_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		pending  int
+		dead     int
+		disabled bool
+	}{
+		{name: "deny send", err: &api.Error{Code: api.ErrMessagesDenySend}, dead: 1},
+		{name: "server", err: &api.Error{Code: api.ErrServer}, pending: 1},
+		{name: "chat gone", err: &api.Error{Code: api.ErrMessagesChatUserNoAccess}, disabled: true},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			s, fake := newTestService(t)
+			defer s.Close(context.Background())
+
+			peerID := int(chatPeerOffset + 1)
+			fake.fail(tt.err)
+
+			if id := s.sendMessage(peerID, "message", nil); id != 0 {
+				t.Errorf("sendMessage() = %d", id)
+			}
+
+			if pending, dead := outboxLen(t, s); pending != tt.pending || dead != tt.dead {
+				t.Errorf("outbox has %d pending and %d dead, want %d and %d", pending, dead, tt.pending, tt.dead)
+			}
+
+			if disabled, _ := s.chatDisabled(peerID); disabled != tt.disabled {
+				t.Errorf("chat disabled = %v, want %v", disabled, tt.disabled)
+			}
+		})
+	}
+}
+
+func TestRetryOutbox_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		pending  int
+		dead     int
+		disabled bool
+	}{
+		{name: "deny send", err: &api.Error{Code: api.ErrMessagesDenySend}, dead: 1},
+		{name: "server", err: &api.Error{Code: api.ErrServer}, pending: 1},
+		{name: "chat gone", err: &api.Error{Code: api.ErrMessagesChatNotExist}, disabled: true},
+		{name: "delivered"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			s, fake := newTestService(t)
+			defer s.Close(context.Background())
+
+			peerID := int(chatPeerOffset + 1)
+			addPending(t, s, peerID)
+			fake.fail(tt.err)
+
+			s.retryOutbox()
+
+			if pending, dead := outboxLen(t, s); pending != tt.pending || dead != tt.dead {
+				t.Errorf("outbox has %d pending and %d dead, want %d and %d", pending, dead, tt.pending, tt.dead)
+			}
+
+			if disabled, _ := s.chatDisabled(peerID); disabled != tt.disabled {
+				t.Errorf("chat disabled = %v, want %v", disabled, tt.disabled)
+			}
+		})
+	}
+}
+
+func TestChatGoneWebhook(t *testing.T) {
+	s, fake := newTestService(t)
+	defer s.Close(context.Background())
+
+	peerID := int(chatPeerOffset + 1)
+
+	token, err := s.generateToken(peerID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake.fail(&api.Error{Code: api.ErrMessagesChatDisabled})
+	s.sendMessage(peerID, "message", nil)
+
+	r := httptest.NewRequest(http.MethodPost, "/webhook/"+strconv.Itoa(peerID), strings.NewReader("{}"))
+	r.Header.Set(gitlab.HeaderEvent, string(gitlab.EventTypePush))
+	r.Header.Set(gitlab.HeaderToken, token)
+
+	w := httptest.NewRecorder()
+	s.router().ServeHTTP(w, r)
+
+	if w.Code != http.StatusGone {
+		t.Errorf("webhook status = %d, want %d", w.Code, http.StatusGone)
+	}
+}
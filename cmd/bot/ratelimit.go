@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strconv"
+
+	"github.com/SevereCloud/gitlabvk/internal"
+	"github.com/SevereCloud/vksdk/v2/api"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// defaultRateLimit is VK API limit for community token
+	defaultRateLimit     = api.LimitGroupToken
+	defaultPeerRateLimit = 3
+	defaultPeerRateBurst = 10
+
+	// maxAttemptTooMany is number of calls on too many requests error. It is
+	// the only retry of the error, after it messages are saved to outbox.
+	maxAttemptTooMany = 3
+)
+
+// envFloat return float from environment variable or def
+func envFloat(key string, def float64) float64 {
+	v := os.Getenv(key)
+	if v == "" {
+		return def
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		log.WithError(err).Fatalf("Invalid %s", key)
+	}
+
+	return f
+}
+
+// initRateLimit put rate limiter in front of every VK API call. Calls with
+// peer_id or user_id also wait for limit of the peer, so one noisy project
+// cannot use the whole limit of the community.
+func (s *Service) initRateLimit() {
+	rate := envFloat("GITLABVK_RATE_LIMIT", defaultRateLimit)
+
+	limiter := internal.NewRateLimiter(
+		rate,
+		int(rate),
+		envFloat("GITLABVK_PEER_RATE_LIMIT", defaultPeerRateLimit),
+		envInt("GITLABVK_PEER_RATE_BURST", defaultPeerRateBurst),
+	)
+
+	next := s.vk.Handler
+
+	s.vk.Handler = func(method string, sliceParams ...api.Params) (api.Response, error) {
+		ctx, peerID := limitParams(sliceParams)
+
+		for attempt := 1; ; attempt++ {
+			if err := limiter.Wait(ctx, peerID); err != nil {
+				return api.Response{}, err
+			}
+
+			resp, err := next(method, sliceParams...)
+			if !errors.Is(err, api.ErrTooMany) || attempt >= maxAttemptTooMany {
+				return resp, err
+			}
+
+			log.WithFields(log.Fields{
+				"method":  method,
+				"peer_id": peerID,
+			}).Warn("VK API too many requests")
+
+			limiter.Drain()
+		}
+	}
+
+	// Built-in limiter is replaced by ours
+	s.vk.Limit = 0
+}
+
+// limitParams return context and peer of VK API call
+func limitParams(sliceParams []api.Params) (ctx context.Context, peerID int) {
+	ctx = context.Background()
+
+	for _, p := range sliceParams {
+		if c, ok := p[":context"].(context.Context); ok {
+			ctx = c
+		}
+
+		for _, key := range []string{"peer_id", "user_id"} {
+			if id, ok := p[key].(int); ok && peerID == 0 {
+				peerID = id
+			}
+		}
+	}
+
+	return ctx, peerID
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/SevereCloud/gitlabvk/pkg/gitlab"
+)
+
+// clearRefPatterns is argument of refs command, which removes all patterns.
+// Git refs can't start with dash, so it can't be a pattern.
+const clearRefPatterns = "-"
+
+// errBadPattern returned for malformed ref pattern
+var errBadPattern = errors.New("bad pattern")
+
+// refPatterns return glob patterns of refs. Patterns with "!" prefix
+// exclude refs.
+func (s *Service) refPatterns(peerID int, hook string) ([]string, error) {
+	value, err := s.getKey(peerID, hookKey(refFiltersKey, hook))
+	if err != nil || value == "" {
+		return nil, err
+	}
+
+	return strings.Fields(value), nil
+}
+
+// setRefPatterns validate and save patterns
+func (s *Service) setRefPatterns(peerID int, hook string, patterns []string) error {
+	for _, p := range patterns {
+		if _, err := path.Match(strings.TrimPrefix(p, "!"), ""); err != nil || p == "!" {
+			return fmt.Errorf("%w %q", errBadPattern, p)
+		}
+	}
+
+	return s.setKey(peerID, hookKey(refFiltersKey, hook), strings.Join(patterns, " "))
+}
+
+// matchRef return true if ref matches at least one include pattern (or
+// there are no include patterns) and doesn't match any exclude pattern.
+func matchRef(patterns []string, ref string) bool {
+	included := true
+
+	for _, p := range patterns {
+		if strings.HasPrefix(p, "!") {
+			if ok, _ := path.Match(p[1:], ref); ok {
+				return false
+			}
+
+			continue
+		}
+
+		included = false
+	}
+
+	if included {
+		return true
+	}
+
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, ref); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// eventRef return branch or tag of event
+func eventRef(v interface{}) (string, bool) {
+	switch e := v.(type) {
+	case *gitlab.EventPush:
+		return baseRef(e.Ref), true
+	case *gitlab.EventTagPush:
+		return baseRef(e.Ref), true
+	case *gitlab.EventJob:
+		return baseRef(e.Ref), true
+	case *gitlab.EventBuild:
+		return baseRef(e.Ref), true
+	case *gitlab.EventPipeline:
+		return baseRef(e.ObjectAttributes.Ref), true
+	default:
+		return "", false
+	}
+}
+
+// refsMessageBuild return patterns of peer with help
+func (s *Service) refsMessageBuild(peerID int, hook string) (string, error) {
+	patterns, err := s.refPatterns(peerID, hook)
+	if err != nil {
+		return "", err
+	}
+
+	text := "Фильтр веток и тегов: "
+	if len(patterns) == 0 {
+		text += "все\n"
+	} else {
+		text += strings.Join(patterns, " ") + "\n"
+	}
+
+	text += "\nФильтр применяется к push, тегам, job и pipeline. " +
+		"Шаблон с ! исключает ветки, * не включает /.\n" +
+		"Изменить: /refs " + hookArg(hook) + "main release/* !renovate/*\n" +
+		"Сбросить: /refs " + hookArg(hook) + clearRefPatterns
+
+	return text, nil
+}
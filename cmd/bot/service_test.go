@@ -0,0 +1,517 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/SevereCloud/gitlabvk/internal"
+	"github.com/SevereCloud/gitlabvk/pkg/gitlab"
+	"github.com/SevereCloud/vksdk/v2/api"
+	"github.com/SevereCloud/vksdk/v2/events"
+	"github.com/SevereCloud/vksdk/v2/object"
+)
+
+const (
+	testPeerID = 1
+	testChatID = int(chatPeerOffset + 1)
+
+	testPushBody = `{"object_kind":"push","ref":"refs/heads/main","user_name":"user",` +
+		`"before":"1111111111111111111111111111111111111111",` +
+		`"after":"2222222222222222222222222222222222222222","project":{"name":"project"}}`
+)
+
+// fakeVK remember messages sent by service
+type fakeVK struct {
+	mtx      sync.Mutex
+	messages []string
+	sendErr  error
+	members  map[int][]int
+}
+
+// fail make messages.send return err
+func (f *fakeVK) fail(err error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	f.sendErr = err
+}
+
+func (f *fakeVK) handler(method string, params ...api.Params) (api.Response, error) {
+	switch method {
+	case "messages.send":
+	case "messages.getConversationMembers":
+		return f.conversationMembers(params...)
+	default:
+		return api.Response{Response: object.RawMessage("{}")}, nil
+	}
+
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if f.sendErr != nil {
+		return api.Response{}, f.sendErr
+	}
+
+	for _, p := range params {
+		if m, ok := p["message"].(string); ok {
+			f.messages = append(f.messages, m)
+		}
+	}
+
+	return api.Response{Response: object.RawMessage(strconv.Itoa(len(f.messages)))}, nil
+}
+
+// conversationMembers return members of chat
+func (f *fakeVK) conversationMembers(params ...api.Params) (api.Response, error) {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	type member struct {
+		MemberID int `json:"member_id"`
+	}
+
+	var members []member
+
+	for _, p := range params {
+		if peerID, ok := p["peer_id"].(int); ok {
+			for _, id := range f.members[peerID] {
+				members = append(members, member{MemberID: id})
+			}
+		}
+	}
+
+	raw, err := json.Marshal(map[string]interface{}{
+		"count": len(members),
+		"items": members,
+	})
+
+	return api.Response{Response: raw}, err
+}
+
+// count return number of sent messages with prefix
+func (f *fakeVK) count(prefix string) int {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	n := 0
+
+	for _, m := range f.messages {
+		if strings.HasPrefix(m, prefix) {
+			n++
+		}
+	}
+
+	return n
+}
+
+// last return last sent message
+func (f *fakeVK) last() string {
+	f.mtx.Lock()
+	defer f.mtx.Unlock()
+
+	if len(f.messages) == 0 {
+		return ""
+	}
+
+	return f.messages[len(f.messages)-1]
+}
+
+// newTestService return service with memory storage and fake VK API
+func newTestService(t *testing.T) (*Service, *fakeVK) {
+	t.Helper()
+
+	fake := &fakeVK{}
+
+	vk := api.NewVK("")
+	vk.Handler = fake.handler
+	vk.Limit = 0
+
+	dedup, err := internal.NewDedup(time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	outbox, err := internal.NewOutbox(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s := &Service{
+		fl:         gitlab.NewFuncList(),
+		vk:         vk,
+		dedup:      dedup,
+		outbox:     outbox,
+		stopOutbox: func() {},
+		verify:     internal.NewVerification("secret"),
+		storage:    internal.NewMemoryStorage(),
+		cache:      internal.NewCache(defaultCacheSize, defaultCacheTTL, defaultCacheNegativeTTL),
+		keyLock:    internal.NewKeyLock(),
+		metrics:    new(expvar.Map).Init(),
+		domain:     "example.com",
+	}
+	s.initEvents()
+
+	return s, fake
+}
+
+// command send text message to bot from user
+func (s *Service) command(fromID int, text string) {
+	var obj events.MessageNewObject
+
+	obj.Message.PeerID = fromID
+	obj.Message.FromID = fromID
+	obj.Message.Text = text
+
+	s.MessageNew(context.Background(), obj)
+}
+
+// run call f concurrently for every i in [0, n)
+func run(n int, f func(i int)) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+			f(i)
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func TestConcurrentCommands(t *testing.T) {
+	s, _ := newTestService(t)
+	defer s.Close(context.Background())
+
+	kinds := eventKinds()
+
+	run(len(kinds)+maxHooks+maxAccountLinks, func(i int) {
+		switch {
+		case i < len(kinds):
+			s.command(testPeerID, "/unsubscribe "+kinds[i].Name)
+		case i < len(kinds)+maxHooks:
+			s.command(testPeerID, "/hook new backend "+strconv.Itoa(i))
+		default:
+			if err := s.addAccountLink(testChatID, testPeerID, "user"+strconv.Itoa(i)); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	disabled, err := s.disabledEvents(testPeerID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(disabled) != len(kinds) {
+		t.Errorf("disabled %d event types, want %d: %v", len(disabled), len(kinds), disabled)
+	}
+
+	hooks, err := s.hooks(testPeerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(hooks) != maxHooks {
+		t.Errorf("created %d webhooks, want %d", len(hooks), maxHooks)
+	}
+
+	links, err := s.accountLinks(testPeerID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(links) != maxAccountLinks {
+		t.Errorf("linked %d accounts, want %d", len(links), maxAccountLinks)
+	}
+}
+
+// linked return true if account is linked in chat
+func linked(links []accountLink, peerID int, name string) bool {
+	for _, l := range links {
+		if l.PeerID == peerID && l.Name == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestConcurrentAccountLink(t *testing.T) {
+	s, _ := newTestService(t)
+	defer s.Close(context.Background())
+
+	run(20, func(i int) {
+		err := s.addAccountLink(testChatID, testPeerID+i%2, "shared")
+		if err != nil && !errors.Is(err, errAccountTaken) {
+			t.Error(err)
+		}
+	})
+
+	owner := s.vkUserOf(testChatID, account{Username: "shared"})
+	if owner != testPeerID && owner != testPeerID+1 {
+		t.Fatalf("account owner = %d", owner)
+	}
+
+	for _, id := range []int{testPeerID, testPeerID + 1} {
+		links, err := s.accountLinks(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if l := linked(links, testChatID, "shared"); l != (id == owner) {
+			t.Errorf("user %d linked = %v, owner %d", id, l, owner)
+		}
+	}
+}
+
+func TestAccountVerification(t *testing.T) {
+	const (
+		owner     = testPeerID
+		squatter  = testPeerID + 1
+		otherChat = testChatID + 1
+	)
+
+	s, fake := newTestService(t)
+	defer s.Close(context.Background())
+
+	fake.members = map[int][]int{
+		testChatID: {owner, squatter},
+		otherChat:  {squatter},
+	}
+
+	s.command(owner, "/link alice")
+
+	code := claimCodeRe.FindString(fake.last())
+	if code == "" {
+		t.Fatalf("no code in message %q", fake.last())
+	}
+
+	alice := gitlab.User{ID: 1, Username: "Alice"}
+
+	s.verifyAccount(testChatID, gitlab.User{ID: 2, Username: "bob"}, "looks good "+code)
+	s.verifyAccount(owner, alice, code)
+	s.verifyAccount(otherChat, alice, code)
+
+	for _, peerID := range []int{testChatID, owner, otherChat} {
+		if id := s.vkUserOf(peerID, account{Username: "alice"}); id != 0 {
+			t.Errorf("account linked to %d in %d without verification", id, peerID)
+		}
+	}
+
+	s.verifyAccount(testChatID, alice, "verify "+code)
+
+	if id := s.vkUserOf(testChatID, account{Username: "alice"}); id != owner {
+		t.Fatalf("account owner = %d, want %d", id, owner)
+	}
+
+	if id := s.vkUserOf(otherChat, account{Username: "alice"}); id != 0 {
+		t.Errorf("account linked in another chat to %d", id)
+	}
+
+	// verified link is not replaced by another verified claim
+	s.command(squatter, "/link alice")
+
+	code = claimCodeRe.FindString(fake.last())
+	s.verifyAccount(testChatID, alice, code)
+
+	if id := s.vkUserOf(testChatID, account{Username: "alice"}); id != owner {
+		t.Errorf("account owner = %d after claim of another user", id)
+	}
+
+	if links, _ := s.accountLinks(squatter); linked(links, testChatID, "alice") {
+		t.Error("account linked by squatter")
+	}
+
+	if err := s.removeAccountLink(owner, "alice"); err != nil {
+		t.Fatal(err)
+	}
+
+	if id := s.vkUserOf(testChatID, account{Username: "alice"}); id != 0 {
+		t.Errorf("account owner = %d after unlink", id)
+	}
+}
+
+func TestConcurrentWebhooks(t *testing.T) {
+	const deliveries = 50
+
+	s, fake := newTestService(t)
+
+	token, err := s.generateToken(testPeerID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	router := s.router()
+
+	run(2*deliveries, func(i int) {
+		if i%2 == 1 {
+			// settings of the same peer are changed by buttons meanwhile
+			switch i % 4 {
+			case 1:
+				s.command(testPeerID, "/unsubscribe job")
+			default:
+				s.command(testPeerID, "/subscribe job")
+			}
+
+			return
+		}
+
+		r := httptest.NewRequest(http.MethodPost, "/webhook/"+strconv.Itoa(testPeerID), strings.NewReader(testPushBody))
+		r.Header.Set(gitlab.HeaderEvent, string(gitlab.EventTypePush))
+		r.Header.Set(gitlab.HeaderToken, token)
+		r.Header.Set(gitlab.HeaderEventUUID, strconv.Itoa(i))
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, r)
+
+		if w.Code != http.StatusOK {
+			t.Errorf("webhook status = %d", w.Code)
+		}
+	})
+
+	// wait for queued events
+	if err := s.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if n := fake.count("🛠"); n != deliveries {
+		t.Errorf("sent %d push messages, want %d", n, deliveries)
+	}
+}
+
+func TestWebhookUnknownPeer(t *testing.T) {
+	s, _ := newTestService(t)
+	defer s.Close(context.Background())
+
+	r := httptest.NewRequest(http.MethodPost, "/webhook/42", strings.NewReader("{}"))
+	r.Header.Set(gitlab.HeaderEvent, string(gitlab.EventTypePush))
+	r.Header.Set(gitlab.HeaderToken, "token")
+
+	w := httptest.NewRecorder()
+	s.router().ServeHTTP(w, r)
+
+	if w.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", w.Code, http.StatusNotFound)
+	}
+
+	if salt, _ := s.storage.Get(42, "salt"); salt != "" {
+		t.Error("salt is created by webhook request")
+	}
+}
+
+func TestIssueFilterAuthor(t *testing.T) {
+	if _, err := parseIssueFilter("author:alice"); err == nil {
+		t.Error("author: with username is accepted")
+	}
+
+	// bob comments issue of alice, no previous events of alice are known
+	var e gitlab.EventIssue
+
+	e.User = gitlab.User{ID: 2, Username: "bob"}
+	e.ObjectAttributes.AuthorID = 1
+
+	sub, ok := filterSubjectOf(&e)
+	if !ok {
+		t.Fatal("no filter subject of issue")
+	}
+
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"author:1", true},
+		{"!author:1", false},
+		{"author:2", false},
+		{"!author:2", true},
+		{"user:bob", true},
+		{"user:@Bob", true},
+		{"user:alice", false},
+	}
+
+	for _, tt := range tests {
+		if got := parseTestFilter(t, tt.filter).Match(sub); got != tt.want {
+			t.Errorf("%s Match() = %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
+
+// parseTestFilter return parsed issue filter
+func parseTestFilter(t *testing.T, text string) issueFilter {
+	t.Helper()
+
+	f, err := parseIssueFilter(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return f
+}
+
+// countingStorage count reads of key
+type countingStorage struct {
+	internal.Storage
+
+	key   string
+	mtx   sync.Mutex
+	reads int
+}
+
+func (c *countingStorage) Get(peerID int, key string) (string, error) {
+	if key == c.key {
+		c.mtx.Lock()
+		c.reads++
+		c.mtx.Unlock()
+	}
+
+	return c.Storage.Get(peerID, key)
+}
+
+func TestWebhookReadsSaltOnce(t *testing.T) {
+	s, _ := newTestService(t)
+	defer s.Close(context.Background())
+
+	storage := &countingStorage{Storage: s.storage, key: "salt"}
+	s.storage = storage
+
+	token, err := s.generateToken(testPeerID, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range []struct {
+		token  string
+		status int
+	}{
+		{token, http.StatusOK},
+		{"wrong", http.StatusForbidden},
+	} {
+		storage.reads = 0
+
+		r := httptest.NewRequest(http.MethodPost, "/webhook/"+strconv.Itoa(testPeerID), strings.NewReader(testPushBody))
+		r.Header.Set(gitlab.HeaderEvent, string(gitlab.EventTypePush))
+		r.Header.Set(gitlab.HeaderToken, tt.token)
+
+		w := httptest.NewRecorder()
+		s.router().ServeHTTP(w, r)
+
+		if w.Code != tt.status {
+			t.Errorf("status = %d, want %d", w.Code, tt.status)
+		}
+
+		if storage.reads != 1 {
+			t.Errorf("salt is read %d times, want 1", storage.reads)
+		}
+	}
+}
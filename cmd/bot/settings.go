@@ -0,0 +1,136 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SevereCloud/gitlabvk/pkg/gitlab"
+)
+
+const (
+	confidentialOn  = "on"
+	confidentialOff = "off"
+	mentionsOff     = "off"
+
+	// defaultEmoji is emoji for approval feed
+	defaultEmoji = "thumbsup"
+)
+
+// confidentialEnabled return true if confidential issues and notes
+// should be sent to peer. Chats get them only after opt-in, because not
+// every member of chat can see them in GitLab. On storage error
+// confidential events are hidden.
+func (s *Service) confidentialEnabled(peerID int) (bool, error) {
+	value, err := s.getKey(peerID, confidentialKey)
+	if err != nil {
+		return false, err
+	}
+
+	switch value {
+	case confidentialOn:
+		return true, nil
+	case confidentialOff:
+		return false, nil
+	default:
+		return !isChat(peerID), nil
+	}
+}
+
+func (s *Service) setConfidential(peerID int, enabled bool) error {
+	value := confidentialOn
+	if !enabled {
+		value = confidentialOff
+	}
+
+	return s.setKey(peerID, confidentialKey, value)
+}
+
+// emojiList return list of emoji names, which should be sent to peer.
+// Empty list disables emoji events.
+func (s *Service) emojiList(peerID int) ([]string, error) {
+	value, err := s.getKey(peerID, emojiKey)
+	if err != nil || value == "" {
+		return nil, err
+	}
+
+	return strings.Split(value, ","), nil
+}
+
+func (s *Service) setEmojiList(peerID int, names []string) error {
+	return s.setKey(peerID, emojiKey, strings.Join(names, ","))
+}
+
+// emojiTypes return awardable types, emoji on which are sent to peer. By
+// default emoji feed is approval of merge requests.
+func (s *Service) emojiTypes(peerID int) ([]string, error) {
+	value, err := s.getKey(peerID, emojiTypesKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if value == "" {
+		return []string{gitlab.AwardableTypeMergeRequest}, nil
+	}
+
+	return strings.Split(value, ","), nil
+}
+
+func (s *Service) setEmojiTypes(peerID int, types []string) error {
+	return s.setKey(peerID, emojiTypesKey, strings.Join(types, ","))
+}
+
+// mentionsEnabled return true if linked users should be mentioned in chat.
+// Private dialogs have no mentions.
+func (s *Service) mentionsEnabled(peerID int) (bool, error) {
+	if !isChat(peerID) {
+		return false, nil
+	}
+
+	value, err := s.getKey(peerID, mentionsKey)
+	if err != nil {
+		return false, err
+	}
+
+	return value != mentionsOff, nil
+}
+
+func (s *Service) setMentions(peerID int, enabled bool) error {
+	value := ""
+	if !enabled {
+		value = mentionsOff
+	}
+
+	return s.setKey(peerID, mentionsKey, value)
+}
+
+// mutedUntil return time, until which notifications of peer are muted. Zero
+// time means notifications are not muted.
+func (s *Service) mutedUntil(peerID int) (time.Time, error) {
+	value, err := s.getKey(peerID, mutedUntilKey)
+	if err != nil || value == "" {
+		return time.Time{}, err
+	}
+
+	unix, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return time.Time{}, nil
+	}
+
+	until := time.Unix(unix, 0)
+	if until.Before(time.Now()) {
+		return time.Time{}, nil
+	}
+
+	return until, nil
+}
+
+// setMutedUntil mute notifications until t, zero t unmutes them
+func (s *Service) setMutedUntil(peerID int, t time.Time) error {
+	value := ""
+	if !t.IsZero() {
+		value = strconv.FormatInt(t.Unix(), 10)
+	}
+
+	return s.setKey(peerID, mutedUntilKey, value)
+}
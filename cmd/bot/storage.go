@@ -1,58 +1,265 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
-	"github.com/SevereCloud/vksdk/v2/api"
+	"github.com/SevereCloud/gitlabvk/internal"
+	_ "github.com/lib/pq" // PostgreSQL driver
 	log "github.com/sirupsen/logrus"
 )
 
 const prefixKey = "gitlabvk_"
 
+// storage types
+const (
+	storageVK       = "vk"
+	storageMemory   = "memory"
+	storageBolt     = "bolt"
+	storageSQLite   = "sqlite"
+	storagePostgres = "postgres"
+
+	defaultBoltFile   = "gitlabvk.db"
+	defaultSQLiteFile = "gitlabvk.sqlite"
+
+	defaultCacheSize        = 10000
+	defaultCacheTTL         = 5 * time.Minute
+	defaultCacheNegativeTTL = time.Minute
+)
+
 // keys
 const (
-	pipelineMessageID = "pipeline_message_id"
-	pipelineLastID    = "pipeline_last_id"
+	pipelineMessageID   = "pipeline_message_id"
+	pipelineLastID      = "pipeline_last_id"
+	deploymentMessageID = "deployment_message_id"
+	deploymentLastID    = "deployment_last_id"
+	confidentialKey     = "confidential"
+	emojiKey            = "emoji"
+	emojiTypesKey       = "emoji_types"
+	chatDisabledKey     = "chat_disabled"
+	disabledEventsKey   = "disabled_events"
+	refFiltersKey       = "ref_filters"
+	issueFilterKey      = "issue_filter"
+	hooksKey            = "hooks"
+	mentionsKey         = "mentions"
+	accountLinksKey     = "gitlab_links"
+	accountClaimKey     = "gitlab_claim"
+	mutedUntilKey       = "muted_until"
 )
 
-func (s *Service) getKey(userID int, key string) string {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
-	// check cache
-	if v, ok := s.storageCache[fmt.Sprintf("%d_%s", userID, key)]; ok {
-		return v
+// newCache return storage cache configured by environment
+func newCache() *internal.Cache {
+	return internal.NewCache(
+		envInt("GITLABVK_CACHE_SIZE", defaultCacheSize),
+		envDuration("GITLABVK_CACHE_TTL", defaultCacheTTL),
+		envDuration("GITLABVK_CACHE_NEGATIVE_TTL", defaultCacheNegativeTTL),
+	)
+}
+
+// newStorage return storage selected by GITLABVK_STORAGE
+func (s *Service) newStorage() (internal.Storage, error) {
+	dsn := os.Getenv("GITLABVK_STORAGE_DSN")
+
+	switch kind := os.Getenv("GITLABVK_STORAGE"); kind {
+	case "", storageVK:
+		return internal.NewVKStorage(s.vk, prefixKey), nil
+	case storageMemory:
+		return internal.NewMemoryStorage(), nil
+	case storageBolt:
+		if dsn == "" {
+			dsn = defaultBoltFile
+		}
+
+		return internal.NewBoltStorage(dsn)
+	case storageSQLite:
+		if !sqliteSupported {
+			return nil, errors.New("sqlite storage requires build with CGO_ENABLED=1, use bolt instead")
+		}
+
+		if dsn == "" {
+			dsn = defaultSQLiteFile
+		}
+
+		return internal.NewSQLStorage("sqlite3", dsn)
+	case storagePostgres:
+		return internal.NewSQLStorage("postgres", dsn)
+	default:
+		return nil, fmt.Errorf("unknown storage type %q", kind)
 	}
+}
 
-	// get from VK API
-	r, err := s.vk.StorageGet(api.Params{
-		"key":     prefixKey + key,
-		"user_id": userID,
-	})
-	if err != nil {
-		log.WithError(err).Fatal("VK API storage.get")
+// settingKeys are keys changed by user from keyboard
+func settingKeys() []string {
+	return []string{"salt", confidentialKey, emojiKey, emojiTypesKey, disabledEventsKey, refFiltersKey, issueFilterKey, hooksKey, mentionsKey, accountLinksKey, mutedUntilKey}
+}
+
+// uncachedKey return true if key is always read from storage. Replicas
+// must agree on salt, otherwise token reset on one replica is not seen by
+// others, on chain message ids, otherwise replicas edit different
+// messages, and on account claims, which are verified by replica received
+// comment.
+func uncachedKey(key string) bool {
+	switch key {
+	case pipelineMessageID, pipelineLastID, deploymentMessageID, deploymentLastID, accountClaimKey:
+		return true
+	default:
+		return key == "salt" || strings.HasPrefix(key, "salt_")
+	}
+}
+
+func cacheKey(userID int, key string) string {
+	return fmt.Sprintf("%d_%s", userID, key)
+}
+
+// getKey return value of key. Error is logged and counted, so callers only
+// need to decide how to degrade.
+func (s *Service) getKey(userID int, key string) (string, error) {
+	k := cacheKey(userID, key)
+
+	if v, ok := s.cache.Get(k); ok {
+		s.metrics.Add("cache_hits", 1)
+		return v, nil
+	}
+
+	// only one request of the key goes to storage, others wait for it and
+	// get value from cache
+	s.keyLock.Lock(k)
+	defer s.keyLock.Unlock(k)
+
+	return s.loadKey(userID, key)
+}
+
+// setKey save value of key. Value is always written to storage, because
+// cached value may be changed by another replica. Error is logged and
+// counted.
+func (s *Service) setKey(userID int, key, value string) error {
+	k := cacheKey(userID, key)
+
+	s.keyLock.Lock(k)
+	defer s.keyLock.Unlock(k)
+
+	return s.storeKey(userID, key, value)
+}
+
+// getOrSetKey return value of key. If key is empty, value returned by
+// newValue is saved. Concurrent calls get the same value.
+func (s *Service) getOrSetKey(userID int, key string, newValue func() string) (string, error) {
+	k := cacheKey(userID, key)
+
+	s.keyLock.Lock(k)
+	defer s.keyLock.Unlock(k)
+
+	value, err := s.loadKey(userID, key)
+	if err != nil || value != "" {
+		return value, err
 	}
 
-	// save cache
-	s.storageCache[fmt.Sprintf("%d_%s", userID, key)] = r[0].Value
+	value = newValue()
 
-	return r[0].Value
+	return value, s.storeKey(userID, key, value)
 }
 
-func (s *Service) setKey(userID int, key, value string) {
-	s.mtx.Lock()
-	defer s.mtx.Unlock()
+// updateKey replace value of key with value returned by update. Key is
+// locked from read to write, so concurrent updates of the key are not lost.
+// update must not use the same key. If update returns error, nothing is
+// written.
+func (s *Service) updateKey(userID int, key string, update func(old string) (string, error)) error {
+	k := cacheKey(userID, key)
 
-	if s.getKey(userID, key) != value {
-		// save cache
-		s.storageCache[fmt.Sprintf("%d_%s", userID, key)] = value
+	s.keyLock.Lock(k)
+	defer s.keyLock.Unlock(k)
 
-		_, err := s.vk.StorageSet(api.Params{
-			"key":     prefixKey + key,
-			"value":   value,
-			"user_id": userID,
-		})
+	old, err := s.loadKey(userID, key)
+	if err != nil {
+		return err
+	}
+
+	value, err := update(old)
+	if err != nil || value == old {
+		return err
+	}
+
+	return s.storeKey(userID, key, value)
+}
+
+// loadKey return value from cache or storage. Caller must hold key lock.
+func (s *Service) loadKey(userID int, key string) (string, error) {
+	k := cacheKey(userID, key)
+
+	if uncachedKey(key) {
+		value, err := s.storage.Get(userID, key)
 		if err != nil {
-			log.WithError(err).Fatal("VK API storage.set")
+			s.storageError("get", userID, key, err)
 		}
+
+		return value, err
 	}
+
+	// value may be loaded while we were waiting for lock
+	if v, ok := s.cache.Get(k); ok {
+		s.metrics.Add("cache_hits", 1)
+		return v, nil
+	}
+
+	s.metrics.Add("cache_misses", 1)
+
+	value, err := s.storage.Get(userID, key)
+	if err != nil {
+		s.storageError("get", userID, key, err)
+		return "", err
+	}
+
+	s.cache.Set(k, value)
+
+	return value, nil
+}
+
+// storeKey save value to storage and cache. Caller must hold key lock.
+func (s *Service) storeKey(userID int, key, value string) error {
+	k := cacheKey(userID, key)
+
+	err := s.storage.Set(userID, key, value)
+	if err != nil {
+		// cached value may be stale now
+		s.cache.Delete(k)
+		s.storageError("set", userID, key, err)
+
+		return err
+	}
+
+	if !uncachedKey(key) {
+		s.cache.Set(k, value)
+	}
+
+	return nil
+}
+
+// invalidatePeer remove settings of peer from cache, so they are loaded
+// from storage on next request.
+func (s *Service) invalidatePeer(userID int) {
+	for _, key := range settingKeys() {
+		s.cache.Delete(cacheKey(userID, key))
+	}
+
+	// list of hooks is loaded again, so hooks created by another replica
+	// are invalidated too
+	hooks, _ := s.hooks(userID)
+	for _, h := range hooks {
+		for _, key := range hookKeys() {
+			s.cache.Delete(cacheKey(userID, hookKey(key, h.ID)))
+		}
+	}
+}
+
+// storageError log storage error and update metrics
+func (s *Service) storageError(op string, userID int, key string, err error) {
+	s.metrics.Add("storage_"+op+"_errors", 1)
+
+	log.WithError(err).WithFields(log.Fields{
+		"userID": userID,
+		"key":    key,
+	}).Error("Storage " + op + " error")
 }
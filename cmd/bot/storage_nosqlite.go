@@ -0,0 +1,7 @@
+//go:build !cgo
+// +build !cgo
+
+package main
+
+// sqliteSupported is false, because SQLite driver requires cgo
+const sqliteSupported = false
@@ -0,0 +1,11 @@
+//go:build cgo
+// +build cgo
+
+package main
+
+import (
+	_ "github.com/mattn/go-sqlite3" // SQLite driver, requires cgo
+)
+
+// sqliteSupported is true if SQLite driver is built in
+const sqliteSupported = true
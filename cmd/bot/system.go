@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/SevereCloud/gitlabvk/pkg/gitlab"
+)
+
+func (s *Service) onSystemHookProject(ctx context.Context, e gitlab.EventSystemHookProject) {
+	var message string
+
+	switch e.EventName {
+	case gitlab.SystemHookProjectCreate:
+		message = fmt.Sprintf("📁 %s created project %s", e.OwnerName, e.PathWithNamespace)
+	case gitlab.SystemHookProjectDestroy:
+		message = fmt.Sprintf("🗑 project %s destroyed", e.PathWithNamespace)
+	case gitlab.SystemHookProjectRename, gitlab.SystemHookProjectTransfer:
+		message = fmt.Sprintf("📁 project %s moved to %s", e.OldPathWithNamespace, e.PathWithNamespace)
+	default:
+		message = fmt.Sprintf("📁 project %s updated", e.PathWithNamespace)
+	}
+
+	if e.ProjectVisibility != "" {
+		message += fmt.Sprintf("\nvisibility: %s", e.ProjectVisibility)
+	}
+
+	userID := getUserID(ctx)
+	s.sendMessage(userID, message, nil)
+}
+
+func (s *Service) onSystemHookTeamMember(ctx context.Context, e gitlab.EventSystemHookTeamMember) {
+	var message string
+
+	switch e.EventName {
+	case gitlab.SystemHookUserAddToTeam:
+		message = fmt.Sprintf("➕ %s (@%s) added to project %s as %s",
+			e.UserName, e.UserUsername, e.PathWithNamespace, e.AccessLevel)
+	case gitlab.SystemHookUserRemoveFromTeam:
+		message = fmt.Sprintf("➖ %s (@%s) removed from project %s",
+			e.UserName, e.UserUsername, e.PathWithNamespace)
+	default:
+		message = fmt.Sprintf("✏️ %s (@%s) is now %s in project %s",
+			e.UserName, e.UserUsername, e.AccessLevel, e.PathWithNamespace)
+	}
+
+	userID := getUserID(ctx)
+	s.sendMessage(userID, message, nil)
+}
+
+func (s *Service) onSystemHookUser(ctx context.Context, e gitlab.EventSystemHookUser) {
+	var message string
+
+	switch e.EventName {
+	case gitlab.SystemHookUserCreate:
+		message = fmt.Sprintf("👤 new user %s (@%s) %s", e.Name, e.Username, e.Email)
+	case gitlab.SystemHookUserDestroy:
+		message = fmt.Sprintf("🗑 user %s (@%s) destroyed", e.Name, e.Username)
+	case gitlab.SystemHookUserRename:
+		message = fmt.Sprintf("👤 user @%s renamed to @%s", e.OldUsername, e.Username)
+	case gitlab.SystemHookUserFailedLogin:
+		message = fmt.Sprintf("⚠️ failed login of user @%s (%s)", e.Username, e.State)
+	default:
+		message = fmt.Sprintf("👤 %s user @%s", e.EventName, e.Username)
+	}
+
+	userID := getUserID(ctx)
+	s.sendMessage(userID, message, nil)
+}
+
+func (s *Service) onSystemHookGroup(ctx context.Context, e gitlab.EventSystemHookGroup) {
+	var message string
+
+	switch e.EventName {
+	case gitlab.SystemHookGroupCreate:
+		message = fmt.Sprintf("👥 new group %s (%s)", e.Name, e.Path)
+	case gitlab.SystemHookGroupDestroy:
+		message = fmt.Sprintf("🗑 group %s (%s) destroyed", e.Name, e.Path)
+	case gitlab.SystemHookGroupRename:
+		message = fmt.Sprintf("👥 group %s moved to %s", e.OldFullPath, e.FullPath)
+	default:
+		message = fmt.Sprintf("👥 %s group %s", e.EventName, e.Path)
+	}
+
+	userID := getUserID(ctx)
+	s.sendMessage(userID, message, nil)
+}
+
+func (s *Service) onSystemHookGroupMember(ctx context.Context, e gitlab.EventSystemHookGroupMember) {
+	var message string
+
+	switch e.EventName {
+	case gitlab.SystemHookUserAddToGroup:
+		message = fmt.Sprintf("➕ %s (@%s) added to group %s as %s",
+			e.UserName, e.UserUsername, e.GroupPath, e.GroupAccess)
+	case gitlab.SystemHookUserRemoveFromGroup:
+		message = fmt.Sprintf("➖ %s (@%s) removed from group %s",
+			e.UserName, e.UserUsername, e.GroupPath)
+	default:
+		message = fmt.Sprintf("✏️ %s (@%s) is now %s in group %s",
+			e.UserName, e.UserUsername, e.GroupAccess, e.GroupPath)
+	}
+
+	userID := getUserID(ctx)
+	s.sendMessage(userID, message, nil)
+}
+
+func (s *Service) onSystemHookRepositoryUpdate(ctx context.Context, e gitlab.EventSystemHookRepositoryUpdate) {
+	message := fmt.Sprintf("🛠 %s updated repository %s\n", e.UserName, e.Project.PathWithNamespace)
+
+	for _, change := range e.Changes {
+		message += "\n" + baseRef(change.Ref)
+	}
+
+	userID := getUserID(ctx)
+	s.sendMessage(userID, message, nil)
+}
@@ -41,33 +41,59 @@ func GenerateRandomString(n int) string {
 	return string(bytes)
 }
 
-func (s *Service) dataToken(userID int) string {
-	salt := s.getKey(userID, "salt")
-	if salt == "" {
-		salt = GenerateRandomString(16)
-		s.setKey(userID, "salt", salt)
+// tokenData return signed data of webhook token. Every named webhook has
+// own salt, so tokens of webhooks are different.
+func tokenData(userID int, hook, salt string) string {
+	if hook != "" {
+		return fmt.Sprintf("%d_%s_%s", userID, hook, salt)
 	}
 
-	p := fmt.Sprintf("%d_%s", userID, salt)
+	return fmt.Sprintf("%d_%s", userID, salt)
+}
 
-	return p
+// dataToken return signed data of webhook token. Salt is created on first
+// request of settings.
+func (s *Service) dataToken(userID int, hook string) (string, error) {
+	salt, err := s.getOrSetKey(userID, hookKey("salt", hook), func() string {
+		return GenerateRandomString(16)
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return tokenData(userID, hook, salt), nil
 }
 
-// check token
-func (s *Service) checkToken(token string, userID int) bool {
-	return token == s.generateToken(userID)
+// webhookSalt return salt of webhook or empty string, if peer never got
+// settings. Salt is only read, so requests to unknown webhooks don't write
+// to storage.
+func (s *Service) webhookSalt(userID int, hook string) (string, error) {
+	return s.getKey(userID, hookKey("salt", hook))
+}
+
+// check token with salt of webhook
+func (s *Service) checkToken(token string, userID int, hook, salt string) bool {
+	if salt == "" {
+		return false
+	}
+
+	return token == s.verify.GenerateToken(tokenData(userID, hook, salt))
 }
 
 // generate token
-func (s *Service) generateToken(userID int) string {
-	p := s.dataToken(userID)
+func (s *Service) generateToken(userID int, hook string) (string, error) {
+	p, err := s.dataToken(userID, hook)
+	if err != nil {
+		return "", err
+	}
 
-	return s.verify.GenerateToken(p)
+	return s.verify.GenerateToken(p), nil
 }
 
-func (s *Service) regenerateToken(userID int) string {
-	s.setKey(userID, "salt", GenerateRandomString(16))
-	p := s.dataToken(userID)
+func (s *Service) regenerateToken(userID int, hook string) (string, error) {
+	if err := s.setKey(userID, hookKey("salt", hook), GenerateRandomString(16)); err != nil {
+		return "", err
+	}
 
-	return s.verify.GenerateToken(p)
+	return s.generateToken(userID, hook)
 }
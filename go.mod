@@ -5,7 +5,10 @@ go 1.17
 require (
 	github.com/SevereCloud/vksdk/v2 v2.16.0
 	github.com/gorilla/mux v1.8.0
+	github.com/lib/pq v1.10.9
+	github.com/mattn/go-sqlite3 v1.14.16
 	github.com/sirupsen/logrus v1.9.2
+	go.etcd.io/bbolt v1.3.6
 )
 
 require (
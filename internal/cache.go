@@ -0,0 +1,123 @@
+// Package internal for project
+package internal
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// cacheEntry is element of Cache
+type cacheEntry struct {
+	key     string
+	value   string
+	expires time.Time
+}
+
+// Cache is LRU cache with TTL. Empty values are missing keys and have
+// separate TTL, so missing keys are not requested again and again, but
+// appear soon after they are created by another replica.
+type Cache struct {
+	size        int
+	ttl         time.Duration
+	negativeTTL time.Duration
+
+	mtx   sync.Mutex
+	lru   *list.List
+	items map[string]*list.Element
+}
+
+// NewCache return Cache with max size. Zero size or ttl disables cache.
+func NewCache(size int, ttl, negativeTTL time.Duration) *Cache {
+	return &Cache{
+		size:        size,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		lru:         list.New(),
+		items:       make(map[string]*list.Element),
+	}
+}
+
+// Get value of key. ok is false if key is missing or expired.
+func (c *Cache) Get(key string) (value string, ok bool) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return "", false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expires) {
+		c.remove(el)
+		return "", false
+	}
+
+	c.lru.MoveToFront(el)
+
+	return entry.value, true
+}
+
+// Set value of key. The least recently used key is removed if cache is
+// full.
+func (c *Cache) Set(key, value string) {
+	ttl := c.ttl
+	if value == "" {
+		ttl = c.negativeTTL
+	}
+
+	if c.size <= 0 || ttl <= 0 {
+		c.Delete(key)
+		return
+	}
+
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	expires := time.Now().Add(ttl)
+
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*cacheEntry)
+		entry.value = value
+		entry.expires = expires
+
+		c.lru.MoveToFront(el)
+
+		return
+	}
+
+	c.items[key] = c.lru.PushFront(&cacheEntry{
+		key:     key,
+		value:   value,
+		expires: expires,
+	})
+
+	for c.lru.Len() > c.size {
+		c.remove(c.lru.Back())
+	}
+}
+
+// Delete key
+func (c *Cache) Delete(key string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		c.remove(el)
+	}
+}
+
+// Len return number of keys, including expired ones.
+func (c *Cache) Len() int {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	return c.lru.Len()
+}
+
+// remove element. Caller must hold mtx.
+func (c *Cache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.items, el.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,120 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCache_LRU(t *testing.T) {
+	c := NewCache(2, time.Hour, time.Hour)
+
+	c.Set("a", "1")
+	c.Set("b", "2")
+
+	// a is used, so b is the least recently used key
+	if v, ok := c.Get("a"); !ok || v != "1" {
+		t.Fatalf("Get(a) = %q, %v", v, ok)
+	}
+
+	c.Set("c", "3")
+
+	if _, ok := c.Get("b"); ok {
+		t.Error("least recently used key is not evicted")
+	}
+
+	for key, want := range map[string]string{"a": "1", "c": "3"} {
+		if v, ok := c.Get(key); !ok || v != want {
+			t.Errorf("Get(%s) = %q, %v, want %q", key, v, ok, want)
+		}
+	}
+
+	// update of existing key doesn't evict
+	c.Set("a", "4")
+
+	if c.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", c.Len())
+	}
+
+	if v, _ := c.Get("a"); v != "4" {
+		t.Errorf("Get(a) = %q after update", v)
+	}
+}
+
+func TestCache_TTL(t *testing.T) {
+	const ttl = 20 * time.Millisecond
+
+	c := NewCache(10, ttl, time.Hour)
+
+	c.Set("a", "1")
+
+	if _, ok := c.Get("a"); !ok {
+		t.Fatal("Get() before TTL = false")
+	}
+
+	time.Sleep(2 * ttl)
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get() after TTL = true")
+	}
+
+	if c.Len() != 0 {
+		t.Errorf("expired key is not removed, Len() = %d", c.Len())
+	}
+}
+
+func TestCache_NegativeTTL(t *testing.T) {
+	const negativeTTL = 20 * time.Millisecond
+
+	c := NewCache(10, time.Hour, negativeTTL)
+
+	c.Set("missing", "")
+	c.Set("present", "1")
+
+	if v, ok := c.Get("missing"); !ok || v != "" {
+		t.Fatalf("Get() of missing key = %q, %v", v, ok)
+	}
+
+	time.Sleep(2 * negativeTTL)
+
+	if _, ok := c.Get("missing"); ok {
+		t.Error("missing key is cached after negative TTL")
+	}
+
+	if _, ok := c.Get("present"); !ok {
+		t.Error("present key expired with negative TTL")
+	}
+
+	// zero negative TTL doesn't cache missing keys
+	c = NewCache(10, time.Hour, 0)
+	c.Set("missing", "")
+
+	if _, ok := c.Get("missing"); ok {
+		t.Error("missing key is cached with zero negative TTL")
+	}
+}
+
+func TestCache_Delete(t *testing.T) {
+	c := NewCache(10, time.Hour, time.Hour)
+
+	c.Set("a", "1")
+	c.Delete("a")
+	c.Delete("unknown")
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("Get() after Delete() = true")
+	}
+
+	if c.Len() != 0 {
+		t.Errorf("Len() = %d after Delete()", c.Len())
+	}
+}
+
+func TestCache_Disabled(t *testing.T) {
+	c := NewCache(0, time.Hour, time.Hour)
+
+	c.Set("a", "1")
+
+	if _, ok := c.Get("a"); ok {
+		t.Error("zero size cache keeps keys")
+	}
+}
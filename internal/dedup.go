@@ -0,0 +1,249 @@
+// Package internal for project
+package internal
+
+import (
+	"bufio"
+	"container/list"
+	"encoding/json"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// dedupCompactThreshold is number of stale records in file, after which
+// file is rewritten
+const dedupCompactThreshold = 1024
+
+// dedupRecord is a line of dedup log file
+type dedupRecord struct {
+	Key    string    `json:"k"`
+	Time   time.Time `json:"t"`
+	Forget bool      `json:"f,omitempty"`
+}
+
+// dedupEntry is key in order of arrival
+type dedupEntry struct {
+	key  string
+	time time.Time
+}
+
+// Dedup remember keys within window. If path is not empty, keys are
+// appended to the file and loaded on start, so they survive restarts.
+//
+// Keys are kept in order of arrival, so expired keys are removed from
+// memory on every call. File is rewritten when it has too many records of
+// expired or forgotten keys.
+type Dedup struct {
+	window time.Duration
+	path   string
+
+	mtx   sync.Mutex
+	seen  map[string]time.Time
+	order *list.List
+	file  *os.File
+	stale int
+}
+
+// NewDedup return Dedup
+func NewDedup(window time.Duration, path string) (*Dedup, error) {
+	d := &Dedup{
+		window: window,
+		path:   path,
+		seen:   make(map[string]time.Time),
+		order:  list.New(),
+	}
+
+	if path == "" {
+		return d, nil
+	}
+
+	if err := d.load(); err != nil {
+		return nil, err
+	}
+
+	if err := d.compact(); err != nil {
+		return nil, err
+	}
+
+	return d, nil
+}
+
+// Seen return true if key was seen within window, otherwise remember it.
+func (d *Dedup) Seen(key string) bool {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	now := time.Now()
+	d.evict(now)
+
+	if _, ok := d.seen[key]; ok {
+		return true
+	}
+
+	d.seen[key] = now
+	d.order.PushBack(dedupEntry{key: key, time: now})
+	d.append(dedupRecord{Key: key, Time: now})
+
+	return false
+}
+
+// Forget key
+func (d *Dedup) Forget(key string) {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	// record of key and forget record itself are stale
+	if _, ok := d.seen[key]; ok {
+		delete(d.seen, key)
+		d.stale++
+	}
+
+	d.stale++
+	d.append(dedupRecord{Key: key, Time: time.Now(), Forget: true})
+}
+
+// evict remove expired keys from memory
+func (d *Dedup) evict(now time.Time) {
+	for e := d.order.Front(); e != nil; e = d.order.Front() {
+		entry := e.Value.(dedupEntry)
+		if now.Sub(entry.time) < d.window {
+			break
+		}
+
+		d.order.Remove(e)
+
+		// key may be forgotten and seen again later
+		if t, ok := d.seen[entry.key]; ok && t.Equal(entry.time) {
+			delete(d.seen, entry.key)
+			d.stale++
+		}
+	}
+}
+
+// Close dedup file
+func (d *Dedup) Close() error {
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if d.file == nil {
+		return nil
+	}
+
+	return d.file.Close()
+}
+
+// load keys from file
+func (d *Dedup) load() error {
+	f, err := os.Open(d.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r dedupRecord
+		if json.Unmarshal(scanner.Bytes(), &r) != nil {
+			// skip broken line, for example after crash
+			continue
+		}
+
+		if r.Forget {
+			delete(d.seen, r.Key)
+		} else {
+			d.seen[r.Key] = r.Time
+		}
+	}
+
+	return scanner.Err()
+}
+
+// compact remove expired keys and rewrite file
+func (d *Dedup) compact() error {
+	now := time.Now()
+	entries := make([]dedupEntry, 0, len(d.seen))
+
+	for key, t := range d.seen {
+		if now.Sub(t) >= d.window {
+			delete(d.seen, key)
+			continue
+		}
+
+		entries = append(entries, dedupEntry{key: key, time: t})
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].time.Before(entries[j].time)
+	})
+
+	d.order.Init()
+
+	for _, entry := range entries {
+		d.order.PushBack(entry)
+	}
+
+	d.stale = 0
+
+	if d.path == "" {
+		return nil
+	}
+
+	if d.file != nil {
+		_ = d.file.Close()
+		d.file = nil
+	}
+
+	tmp := d.path + ".tmp"
+
+	f, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(f)
+	enc := json.NewEncoder(w)
+
+	for _, entry := range entries {
+		if err := enc.Encode(dedupRecord{Key: entry.key, Time: entry.time}); err != nil {
+			f.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(tmp, d.path); err != nil {
+		return err
+	}
+
+	d.file, err = os.OpenFile(d.path, os.O_APPEND|os.O_WRONLY, 0600)
+
+	return err
+}
+
+// append record to file. File is rewritten when stale records outnumber
+// live keys.
+func (d *Dedup) append(r dedupRecord) {
+	if d.file == nil {
+		return
+	}
+
+	raw, _ := json.Marshal(r)
+	_, _ = d.file.Write(append(raw, '\n'))
+
+	if d.stale >= dedupCompactThreshold && d.stale >= len(d.seen) {
+		_ = d.compact()
+	}
+}
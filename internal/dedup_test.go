@@ -0,0 +1,132 @@
+package internal
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// countLines return number of lines in file
+func countLines(t *testing.T, path string) int {
+	t.Helper()
+
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	n := 0
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		n++
+	}
+
+	return n
+}
+
+func TestDedup_Seen(t *testing.T) {
+	d, err := NewDedup(time.Hour, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if d.Seen("a") {
+		t.Error("first Seen() = true")
+	}
+
+	if !d.Seen("a") {
+		t.Error("second Seen() = false")
+	}
+
+	d.Forget("a")
+
+	if d.Seen("a") {
+		t.Error("Seen() after Forget() = true")
+	}
+}
+
+func TestDedup_Evict(t *testing.T) {
+	const (
+		window = 50 * time.Millisecond
+		keys   = dedupCompactThreshold + 100
+	)
+
+	path := filepath.Join(t.TempDir(), "dedup.log")
+
+	d, err := NewDedup(window, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	for i := 0; i < keys; i++ {
+		d.Seen(strconv.Itoa(i))
+	}
+
+	if n := countLines(t, path); n != keys {
+		t.Fatalf("file has %d lines, want %d", n, keys)
+	}
+
+	time.Sleep(2 * window)
+
+	if d.Seen("0") {
+		t.Error("Seen() of expired key = true")
+	}
+
+	d.mtx.Lock()
+	seen, order := len(d.seen), d.order.Len()
+	d.mtx.Unlock()
+
+	if seen != 1 || order != 1 {
+		t.Errorf("memory has %d keys and %d entries, want 1", seen, order)
+	}
+
+	if n := countLines(t, path); n != 1 {
+		t.Errorf("file has %d lines after compaction, want 1", n)
+	}
+
+	if err := d.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err = NewDedup(window, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	if !d.Seen("0") {
+		t.Error("Seen() after restart = false")
+	}
+}
+
+func TestDedup_ForgetCompact(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dedup.log")
+
+	d, err := NewDedup(time.Hour, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	d.Seen("kept")
+
+	for i := 0; i < dedupCompactThreshold; i++ {
+		key := strconv.Itoa(i)
+		d.Seen(key)
+		d.Forget(key)
+	}
+
+	if n := countLines(t, path); n > dedupCompactThreshold {
+		t.Errorf("file has %d lines, want compaction", n)
+	}
+
+	if !d.Seen("kept") {
+		t.Error("Seen() of kept key = false")
+	}
+}
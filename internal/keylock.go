@@ -0,0 +1,60 @@
+// Package internal for project
+package internal
+
+import "sync"
+
+// keyMutex is mutex with number of goroutines, which use it
+type keyMutex struct {
+	sync.Mutex
+	refs int
+}
+
+// KeyLock is set of mutexes by key. Mutex of key is removed when nobody
+// holds or waits for it, so number of mutexes is bounded by number of
+// goroutines.
+type KeyLock struct {
+	mtx   sync.Mutex
+	locks map[string]*keyMutex
+}
+
+// NewKeyLock return KeyLock
+func NewKeyLock() *KeyLock {
+	return &KeyLock{
+		locks: make(map[string]*keyMutex),
+	}
+}
+
+// Lock key
+func (l *KeyLock) Lock(key string) {
+	l.mtx.Lock()
+
+	m, ok := l.locks[key]
+	if !ok {
+		m = &keyMutex{}
+		l.locks[key] = m
+	}
+
+	m.refs++
+
+	l.mtx.Unlock()
+
+	m.Lock()
+}
+
+// Unlock key
+func (l *KeyLock) Unlock(key string) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	m, ok := l.locks[key]
+	if !ok {
+		panic("internal: unlock of unlocked key " + key)
+	}
+
+	m.Unlock()
+
+	m.refs--
+	if m.refs == 0 {
+		delete(l.locks, key)
+	}
+}
@@ -0,0 +1,261 @@
+// Package internal for project
+package internal
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	outboxPending = "pending"
+	outboxDead    = "dead"
+	outboxExt     = ".json"
+)
+
+// ErrInvalidOutboxID returned for malformed message id
+var ErrInvalidOutboxID = errors.New("invalid outbox message id")
+
+// OutboxMessage is undelivered message
+type OutboxMessage struct {
+	ID          string    `json:"id"`
+	PeerID      int       `json:"peer_id"`
+	Message     string    `json:"message"`
+	Keyboard    string    `json:"keyboard,omitempty"`
+	Mentions    bool      `json:"mentions,omitempty"`
+	Attempts    int       `json:"attempts"`
+	NextAttempt time.Time `json:"next_attempt"`
+	LastError   string    `json:"last_error,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Outbox stores undelivered messages in directory. Every message is a file
+// in pending or dead subdirectory, so outbox can be changed by another
+// process, for example by CLI.
+type Outbox struct {
+	dir string
+}
+
+// NewOutbox return Outbox
+func NewOutbox(dir string) (*Outbox, error) {
+	for _, sub := range []string{outboxPending, outboxDead} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0700); err != nil {
+			return nil, err
+		}
+	}
+
+	return &Outbox{dir: dir}, nil
+}
+
+// Add message to pending list
+func (o *Outbox) Add(m OutboxMessage) error {
+	return o.add(outboxPending, m)
+}
+
+// AddDead add message to dead list
+func (o *Outbox) AddDead(m OutboxMessage) error {
+	return o.add(outboxDead, m)
+}
+
+func (o *Outbox) add(sub string, m OutboxMessage) error {
+	if m.ID == "" {
+		id, err := newOutboxID()
+		if err != nil {
+			return err
+		}
+
+		m.ID = id
+	}
+
+	if m.CreatedAt.IsZero() {
+		m.CreatedAt = time.Now()
+	}
+
+	return o.write(sub, m)
+}
+
+// Update pending message
+func (o *Outbox) Update(m OutboxMessage) error {
+	return o.write(outboxPending, m)
+}
+
+// Remove pending message
+func (o *Outbox) Remove(id string) error {
+	path, err := o.path(outboxPending, id)
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(path)
+}
+
+// Kill move pending message to dead list
+func (o *Outbox) Kill(m OutboxMessage) error {
+	if err := o.write(outboxDead, m); err != nil {
+		return err
+	}
+
+	err := o.Remove(m.ID)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	return err
+}
+
+// Pending return pending messages
+func (o *Outbox) Pending() ([]OutboxMessage, error) {
+	return o.list(outboxPending)
+}
+
+// Dead return dead messages
+func (o *Outbox) Dead() ([]OutboxMessage, error) {
+	return o.list(outboxDead)
+}
+
+// Replay move dead message with id to pending list. If id is empty all dead
+// messages are moved.
+func (o *Outbox) Replay(id string) (int, error) {
+	dead, err := o.Dead()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+
+	for _, m := range dead {
+		if id != "" && m.ID != id {
+			continue
+		}
+
+		m.Attempts = 0
+		m.NextAttempt = time.Time{}
+
+		if err := o.write(outboxPending, m); err != nil {
+			return n, err
+		}
+
+		path, _ := o.path(outboxDead, m.ID)
+		if err := os.Remove(path); err != nil {
+			return n, err
+		}
+
+		n++
+	}
+
+	if id != "" && n == 0 {
+		return 0, fmt.Errorf("message %s not found", id)
+	}
+
+	return n, nil
+}
+
+// TrimDead remove dead messages older than maxAge and the oldest messages
+// above max. It return number of removed messages.
+func (o *Outbox) TrimDead(maxAge time.Duration, max int) (int, error) {
+	dead, err := o.Dead()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	now := time.Now()
+
+	for i, m := range dead {
+		if len(dead)-i <= max && now.Sub(m.CreatedAt) < maxAge {
+			break
+		}
+
+		path, _ := o.path(outboxDead, m.ID)
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return n, err
+		}
+
+		n++
+	}
+
+	return n, nil
+}
+
+func (o *Outbox) path(sub, id string) (string, error) {
+	if id == "" || filepath.Base(id) != id || strings.HasPrefix(id, ".") {
+		return "", ErrInvalidOutboxID
+	}
+
+	return filepath.Join(o.dir, sub, id+outboxExt), nil
+}
+
+// write message atomically
+func (o *Outbox) write(sub string, m OutboxMessage) error {
+	path, err := o.path(sub, m.ID)
+	if err != nil {
+		return err
+	}
+
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, raw, 0600); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+func (o *Outbox) list(sub string) ([]OutboxMessage, error) {
+	files, err := ioutil.ReadDir(filepath.Join(o.dir, sub))
+	if err != nil {
+		return nil, err
+	}
+
+	messages := make([]OutboxMessage, 0, len(files))
+
+	for _, f := range files {
+		if f.IsDir() || filepath.Ext(f.Name()) != outboxExt {
+			continue
+		}
+
+		raw, err := ioutil.ReadFile(filepath.Join(o.dir, sub, f.Name()))
+		if err != nil {
+			if os.IsNotExist(err) {
+				// moved by another process
+				continue
+			}
+
+			return nil, err
+		}
+
+		var m OutboxMessage
+		if err := json.Unmarshal(raw, &m); err != nil {
+			return nil, fmt.Errorf("outbox %s: %w", f.Name(), err)
+		}
+
+		messages = append(messages, m)
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+
+	return messages, nil
+}
+
+func newOutboxID() (string, error) {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%d-%s", time.Now().UnixNano(), hex.EncodeToString(b)), nil
+}
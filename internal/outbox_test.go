@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"testing"
+	"time"
+)
+
+func TestOutbox_TrimDead(t *testing.T) {
+	o, err := NewOutbox(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+
+	messages := []OutboxMessage{
+		{ID: "old", CreatedAt: now.Add(-48 * time.Hour)},
+		{ID: "a", CreatedAt: now.Add(-3 * time.Minute)},
+		{ID: "b", CreatedAt: now.Add(-2 * time.Minute)},
+		{ID: "c", CreatedAt: now.Add(-time.Minute), Mentions: true},
+	}
+
+	for _, m := range messages {
+		if err := o.AddDead(m); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	n, err := o.TrimDead(24*time.Hour, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if n != 2 {
+		t.Errorf("TrimDead() = %d, want 2", n)
+	}
+
+	dead, err := o.Dead()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(dead) != 2 || dead[0].ID != "b" || dead[1].ID != "c" {
+		t.Fatalf("Dead() = %+v, want b and c", dead)
+	}
+
+	if !dead[1].Mentions {
+		t.Error("Mentions flag is lost")
+	}
+}
@@ -0,0 +1,164 @@
+// Package internal for project
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// Queue errors
+var (
+	ErrQueueFull   = errors.New("queue is full")
+	ErrQueueClosed = errors.New("queue is closed")
+)
+
+// Queue is bounded job queue with a queue per key and shared workers. Jobs
+// with the same key are run in order, one at a time, so a slow key takes
+// only one worker and other keys are served by the rest. Keys with pending
+// jobs are served in turn. Total number of buffered jobs of all keys is
+// limited by capacity.
+type Queue struct {
+	size     int
+	capacity int
+	wg       sync.WaitGroup
+
+	mtx     sync.Mutex
+	cond    *sync.Cond
+	jobs    map[int][]func()
+	running map[int]bool
+	ready   []int
+	total   int
+	closed  bool
+}
+
+// NewQueue return Queue with workers, size of buffer per key and capacity
+// of all buffers
+func NewQueue(workers, size, capacity int) *Queue {
+	if workers < 1 {
+		workers = 1
+	}
+
+	q := &Queue{
+		size:     size,
+		capacity: capacity,
+		jobs:     make(map[int][]func()),
+		running:  make(map[int]bool),
+	}
+	q.cond = sync.NewCond(&q.mtx)
+
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+
+		go q.worker()
+	}
+
+	return q
+}
+
+// next return key and its job to run. It returns false after Close, when
+// there are no jobs left.
+func (q *Queue) next() (int, func(), bool) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	for len(q.ready) == 0 {
+		if q.closed {
+			return 0, nil, false
+		}
+
+		q.cond.Wait()
+	}
+
+	key := q.ready[0]
+	q.ready = q.ready[1:]
+
+	jobs := q.jobs[key]
+	if len(jobs) == 1 {
+		delete(q.jobs, key)
+	} else {
+		q.jobs[key] = jobs[1:]
+	}
+
+	q.running[key] = true
+	q.total--
+
+	return key, jobs[0], true
+}
+
+// done return key to the end of ready list, if it has more jobs
+func (q *Queue) done(key int) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	delete(q.running, key)
+
+	if len(q.jobs[key]) > 0 {
+		q.ready = append(q.ready, key)
+		q.cond.Signal()
+	}
+}
+
+func (q *Queue) worker() {
+	defer q.wg.Done()
+
+	for {
+		key, job, ok := q.next()
+		if !ok {
+			return
+		}
+
+		job()
+		q.done(key)
+	}
+}
+
+// Push job to queue. It returns ErrQueueFull if buffer of the key or the
+// whole queue is full and ErrQueueClosed after Close.
+func (q *Queue) Push(key int, job func()) error {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+
+	if q.closed {
+		return ErrQueueClosed
+	}
+
+	jobs := q.jobs[key]
+	if len(jobs) >= q.size || q.total >= q.capacity {
+		return ErrQueueFull
+	}
+
+	q.jobs[key] = append(jobs, job)
+	q.total++
+
+	// running key is returned to ready list by its worker
+	if len(jobs) == 0 && !q.running[key] {
+		q.ready = append(q.ready, key)
+		q.cond.Signal()
+	}
+
+	return nil
+}
+
+// Close stop accepting jobs and wait until queued jobs are done or ctx is
+// canceled.
+func (q *Queue) Close(ctx context.Context) error {
+	q.mtx.Lock()
+	q.closed = true
+	q.cond.Broadcast()
+	q.mtx.Unlock()
+
+	done := make(chan struct{})
+
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,176 @@
+package internal
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQueue_Order(t *testing.T) {
+	q := NewQueue(4, 100, 1000)
+
+	var (
+		mtx sync.Mutex
+		got []int
+	)
+
+	for i := 0; i < 100; i++ {
+		i := i
+
+		if err := q.Push(1, func() {
+			mtx.Lock()
+			got = append(got, i)
+			mtx.Unlock()
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(got) != 100 {
+		t.Fatalf("run %d jobs, want 100", len(got))
+	}
+
+	for i, v := range got {
+		if v != i {
+			t.Fatalf("job %d run at %d", v, i)
+		}
+	}
+}
+
+func TestQueue_SlowKey(t *testing.T) {
+	q := NewQueue(2, 10, 100)
+	defer q.Close(context.Background())
+
+	block := make(chan struct{})
+	defer close(block)
+
+	// jobs of slow key wait, but take only one worker
+	for i := 0; i < 5; i++ {
+		if err := q.Push(2, func() { <-block }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	done := make(chan struct{}, 10)
+
+	for key := 4; key < 14; key += 2 {
+		if err := q.Push(key, func() { done <- struct{}{} }); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for i := 0; i < 5; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("jobs of other keys are blocked by slow key")
+		}
+	}
+}
+
+func TestQueue_Full(t *testing.T) {
+	q := NewQueue(1, 1, 10)
+
+	block := make(chan struct{})
+
+	if err := q.Push(1, func() { <-block }); err != nil {
+		t.Fatal(err)
+	}
+
+	// wait until worker takes first job
+	for {
+		q.mtx.Lock()
+		running := q.running[1]
+		q.mtx.Unlock()
+
+		if running {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := q.Push(1, func() {}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.Push(1, func() {}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Push() error = %v, want %v", err, ErrQueueFull)
+	}
+
+	if err := q.Push(2, func() {}); err != nil {
+		t.Errorf("Push() of other key error = %v", err)
+	}
+
+	close(block)
+
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := q.Push(1, func() {}); !errors.Is(err, ErrQueueClosed) {
+		t.Errorf("Push() error = %v, want %v", err, ErrQueueClosed)
+	}
+}
+
+func TestQueue_Capacity(t *testing.T) {
+	const capacity = 5
+
+	q := NewQueue(1, 2, capacity)
+
+	block := make(chan struct{})
+
+	if err := q.Push(0, func() { <-block }); err != nil {
+		t.Fatal(err)
+	}
+
+	// wait until worker takes first job, so it is not buffered
+	for {
+		q.mtx.Lock()
+		running := q.running[0]
+		q.mtx.Unlock()
+
+		if running {
+			break
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	// every key has room, but the whole queue is full
+	for key := 1; key <= capacity; key++ {
+		if err := q.Push(key, func() {}); err != nil {
+			t.Fatalf("Push(%d) error = %v", key, err)
+		}
+	}
+
+	if err := q.Push(capacity+1, func() {}); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("Push() over capacity error = %v, want %v", err, ErrQueueFull)
+	}
+
+	close(block)
+
+	// buffered jobs are taken by worker and free capacity
+	for {
+		err := q.Push(capacity+1, func() {})
+		if err == nil {
+			break
+		}
+
+		if !errors.Is(err, ErrQueueFull) {
+			t.Fatal(err)
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
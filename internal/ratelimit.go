@@ -0,0 +1,199 @@
+// Package internal for project
+package internal
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBucket is token bucket rate limiter. Bucket holds up to burst tokens
+// and is refilled with rate tokens per second.
+type TokenBucket struct {
+	rate  float64
+	burst float64
+
+	mtx    sync.Mutex
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket return full TokenBucket
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &TokenBucket{
+		rate:   rate,
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// refill add tokens for time since last refill. Caller must hold mtx.
+func (b *TokenBucket) refill(now time.Time) {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+
+	b.last = now
+}
+
+// reserve take token and return time to wait before it can be used
+func (b *TokenBucket) reserve() time.Duration {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.refill(time.Now())
+	b.tokens--
+
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+// cancel return reserved token
+func (b *TokenBucket) cancel() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.tokens++
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+}
+
+// Wait take token, blocking until it is available or ctx is canceled.
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	if b == nil || b.rate <= 0 {
+		return nil
+	}
+
+	d := b.reserve()
+	if d == 0 {
+		return nil
+	}
+
+	t := time.NewTimer(d)
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		b.cancel()
+		return ctx.Err()
+	}
+}
+
+// Drain remove all tokens, for example after server said that we are too
+// fast.
+func (b *TokenBucket) Drain() {
+	if b == nil {
+		return
+	}
+
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.refill(time.Now())
+
+	if b.tokens > 0 {
+		b.tokens = 0
+	}
+}
+
+// idle return true if bucket is full, so it can be dropped without changing
+// behavior.
+func (b *TokenBucket) idle(now time.Time) bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+
+	b.refill(now)
+
+	return b.tokens >= b.burst
+}
+
+// maxIdleKeys is number of per-key buckets after which full buckets are
+// removed.
+const maxIdleKeys = 1024
+
+// RateLimiter is global token bucket with token bucket per key. Key bucket
+// is checked first, so one key cannot use the whole global limit.
+type RateLimiter struct {
+	global *TokenBucket
+
+	keyRate  float64
+	keyBurst int
+
+	mtx  sync.Mutex
+	keys map[int]*TokenBucket
+}
+
+// NewRateLimiter return RateLimiter. Zero rate disables the limit.
+func NewRateLimiter(rate float64, burst int, keyRate float64, keyBurst int) *RateLimiter {
+	l := &RateLimiter{
+		keyRate:  keyRate,
+		keyBurst: keyBurst,
+		keys:     make(map[int]*TokenBucket),
+	}
+
+	if rate > 0 {
+		l.global = NewTokenBucket(rate, burst)
+	}
+
+	return l
+}
+
+// Wait take token of key and global token. Zero key uses only global limit.
+func (l *RateLimiter) Wait(ctx context.Context, key int) error {
+	if key != 0 {
+		if err := l.bucket(key).Wait(ctx); err != nil {
+			return err
+		}
+	}
+
+	return l.global.Wait(ctx)
+}
+
+// Drain remove all global tokens
+func (l *RateLimiter) Drain() {
+	l.global.Drain()
+}
+
+func (l *RateLimiter) bucket(key int) *TokenBucket {
+	if l.keyRate <= 0 {
+		return nil
+	}
+
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	b, ok := l.keys[key]
+	if !ok {
+		if len(l.keys) >= maxIdleKeys {
+			l.cleanup()
+		}
+
+		b = NewTokenBucket(l.keyRate, l.keyBurst)
+		l.keys[key] = b
+	}
+
+	return b
+}
+
+// cleanup remove full buckets. Caller must hold mtx.
+func (l *RateLimiter) cleanup() {
+	now := time.Now()
+
+	for key, b := range l.keys {
+		if b.idle(now) {
+			delete(l.keys, key)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Package internal for project
+package internal
+
+import "sync"
+
+// Storage is key-value store of peer settings. Missing key has empty value.
+type Storage interface {
+	Get(peerID int, key string) (string, error)
+	Set(peerID int, key, value string) error
+	Close() error
+}
+
+// MemoryStorage is Storage in memory. Values are lost on restart.
+type MemoryStorage struct {
+	mtx    sync.RWMutex
+	values map[int]map[string]string
+}
+
+// NewMemoryStorage return MemoryStorage
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{
+		values: make(map[int]map[string]string),
+	}
+}
+
+// Get value of key
+func (m *MemoryStorage) Get(peerID int, key string) (string, error) {
+	m.mtx.RLock()
+	defer m.mtx.RUnlock()
+
+	return m.values[peerID][key], nil
+}
+
+// Set value of key
+func (m *MemoryStorage) Set(peerID int, key, value string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+
+	if value == "" {
+		delete(m.values[peerID], key)
+		return nil
+	}
+
+	if m.values[peerID] == nil {
+		m.values[peerID] = make(map[string]string)
+	}
+
+	m.values[peerID][key] = value
+
+	return nil
+}
+
+// Close storage
+func (m *MemoryStorage) Close() error {
+	return nil
+}
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+// boltBucket is bucket with all keys
+const boltBucket = "gitlabvk"
+
+// BoltStorage is Storage in local BoltDB file.
+type BoltStorage struct {
+	db *bolt.DB
+}
+
+// NewBoltStorage open or create BoltDB file
+func NewBoltStorage(path string) (*BoltStorage, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists([]byte(boltBucket))
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &BoltStorage{db: db}, nil
+}
+
+func boltKey(peerID int, key string) []byte {
+	return []byte(strconv.Itoa(peerID) + ":" + key)
+}
+
+// Get value of key
+func (b *BoltStorage) Get(peerID int, key string) (value string, err error) {
+	err = b.db.View(func(tx *bolt.Tx) error {
+		value = string(tx.Bucket([]byte(boltBucket)).Get(boltKey(peerID, key)))
+		return nil
+	})
+
+	return
+}
+
+// Set value of key
+func (b *BoltStorage) Set(peerID int, key, value string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket([]byte(boltBucket))
+		if value == "" {
+			return bucket.Delete(boltKey(peerID, key))
+		}
+
+		return bucket.Put(boltKey(peerID, key), []byte(value))
+	})
+}
+
+// Close database
+func (b *BoltStorage) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,66 @@
+package internal
+
+import (
+	"database/sql"
+	"errors"
+)
+
+// SQLStorage is Storage in SQL database. SQLite and PostgreSQL are
+// supported, driver must be registered by caller.
+type SQLStorage struct {
+	db *sql.DB
+
+	get string
+	set string
+}
+
+// NewSQLStorage open database and create table if it is missing. Driver is
+// "sqlite3" or "postgres".
+func NewSQLStorage(driver, dsn string) (*SQLStorage, error) {
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS gitlabvk_storage (
+		peer_id BIGINT NOT NULL,
+		key TEXT NOT NULL,
+		value TEXT NOT NULL,
+		PRIMARY KEY (peer_id, key)
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	// SQLite and PostgreSQL both support $N placeholders and upsert
+	return &SQLStorage{
+		db:  db,
+		get: `SELECT value FROM gitlabvk_storage WHERE peer_id = $1 AND key = $2`,
+		set: `INSERT INTO gitlabvk_storage (peer_id, key, value) VALUES ($1, $2, $3)
+			ON CONFLICT (peer_id, key) DO UPDATE SET value = excluded.value`,
+	}, nil
+}
+
+// Get value of key
+func (s *SQLStorage) Get(peerID int, key string) (string, error) {
+	var value string
+
+	err := s.db.QueryRow(s.get, peerID, key).Scan(&value)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+
+	return value, err
+}
+
+// Set value of key
+func (s *SQLStorage) Set(peerID int, key, value string) error {
+	_, err := s.db.Exec(s.set, peerID, key, value)
+	return err
+}
+
+// Close database
+func (s *SQLStorage) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,53 @@
+package internal
+
+import (
+	"github.com/SevereCloud/vksdk/v2/api"
+)
+
+// VKStorage is Storage in VK storage.get/storage.set. Keys are prefixed,
+// because VK storage is shared with other applications of the community.
+type VKStorage struct {
+	vk     *api.VK
+	prefix string
+}
+
+// NewVKStorage return VKStorage
+func NewVKStorage(vk *api.VK, prefix string) *VKStorage {
+	return &VKStorage{
+		vk:     vk,
+		prefix: prefix,
+	}
+}
+
+// Get value of key
+func (v *VKStorage) Get(peerID int, key string) (string, error) {
+	r, err := v.vk.StorageGet(api.Params{
+		"key":     v.prefix + key,
+		"user_id": peerID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if len(r) == 0 {
+		return "", nil
+	}
+
+	return r[0].Value, nil
+}
+
+// Set value of key
+func (v *VKStorage) Set(peerID int, key, value string) error {
+	_, err := v.vk.StorageSet(api.Params{
+		"key":     v.prefix + key,
+		"value":   value,
+		"user_id": peerID,
+	})
+
+	return err
+}
+
+// Close storage
+func (v *VKStorage) Close() error {
+	return nil
+}
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"strconv"
-	"time"
 )
 
 // NullSHA null SHA
@@ -13,8 +12,10 @@ const NullSHA = "0000000000000000000000000000000000000000"
 
 // Gitlab header
 const (
-	HeaderEvent = "X-Gitlab-Event"
-	HeaderToken = "X-Gitlab-Token" // nolint: gosec
+	HeaderEvent       = "X-Gitlab-Event"
+	HeaderToken       = "X-Gitlab-Token" // nolint: gosec
+	HeaderEventUUID   = "X-Gitlab-Event-UUID"
+	HeaderWebhookUUID = "X-Gitlab-Webhook-UUID"
 )
 
 // Ci/CD status
@@ -43,6 +44,13 @@ const (
 	EventTypePipeline          EventType = "Pipeline Hook"
 	EventTypeBuild             EventType = "Build Hook"
 	EventTypeWikiPage          EventType = "Wiki Page Hook"
+	EventTypeDeployment        EventType = "Deployment Hook"
+	EventTypeRelease           EventType = "Release Hook"
+	EventTypeMember            EventType = "Member Hook"
+	EventTypeSubgroup          EventType = "Subgroup Hook"
+	EventTypeProject           EventType = "Project Hook"
+	EventTypeEmoji             EventType = "Emoji Hook"
+	EventTypeFeatureFlag       EventType = "Feature Flag Hook"
 	EventTypeSystemHook        EventType = "System Hook"
 )
 
@@ -66,8 +74,8 @@ type EventNote struct {
 		Note         string      `json:"note"`
 		NoteableType string      `json:"noteable_type"`
 		AuthorID     int         `json:"author_id"`
-		CreatedAt    string      `json:"created_at"`
-		UpdatedAt    string      `json:"updated_at"`
+		CreatedAt    Time        `json:"created_at"`
+		UpdatedAt    Time        `json:"updated_at"`
 		ProjectID    int         `json:"project_id"`
 		Attachment   interface{} `json:"attachment"`
 		LineCode     string      `json:"line_code"`
@@ -78,10 +86,10 @@ type EventNote struct {
 		URL          string      `json:"url"`
 	} `json:"object_attributes"`
 	Commit struct {
-		ID        string    `json:"id"`
-		Message   string    `json:"message"`
-		Timestamp time.Time `json:"timestamp"`
-		URL       string    `json:"url"`
+		ID        string `json:"id"`
+		Message   string `json:"message"`
+		Timestamp Time   `json:"timestamp"`
+		URL       string `json:"url"`
 		Author    struct {
 			Name  string `json:"name"`
 			Email string `json:"email"`
@@ -94,8 +102,8 @@ type EventNote struct {
 		Content                string      `json:"content"`
 		AuthorID               int         `json:"author_id"`
 		ProjectID              int         `json:"project_id"`
-		CreatedAt              string      `json:"created_at"`
-		UpdatedAt              string      `json:"updated_at"`
+		CreatedAt              Time        `json:"created_at"`
+		UpdatedAt              Time        `json:"updated_at"`
 		FileName               string      `json:"file_name"`
 		Type                   string      `json:"type"`
 		VisibilityLevel        int         `json:"visibility_level"`
@@ -113,8 +121,8 @@ type EventNote struct {
 		AuthorID                  int         `json:"author_id"`
 		AssigneeID                int         `json:"assignee_id"`
 		Title                     string      `json:"title"`
-		CreatedAt                 string      `json:"created_at"`
-		UpdatedAt                 string      `json:"updated_at"`
+		CreatedAt                 Time        `json:"created_at"`
+		UpdatedAt                 Time        `json:"updated_at"`
 		MilestoneID               int         `json:"milestone_id"`
 		State                     string      `json:"state"`
 		MergeStatus               string      `json:"merge_status"`
@@ -122,7 +130,7 @@ type EventNote struct {
 		IID                       int         `json:"iid"`
 		Description               string      `json:"description"`
 		Position                  int         `json:"position"`
-		LockedAt                  string      `json:"locked_at"`
+		LockedAt                  Time        `json:"locked_at"`
 		UpdatedByID               int         `json:"updated_by_id"`
 		MergeError                string      `json:"merge_error"`
 		MergeParams               MergeParams `json:"merge_params"`
@@ -131,21 +139,21 @@ type EventNote struct {
 		WorkInProgress            bool        `json:"work_in_progress"`
 		MergeUserID               int         `json:"merge_user_id"`
 		MergeCommitSHA            string      `json:"merge_commit_sha"`
-		DeletedAt                 string      `json:"deleted_at"`
+		DeletedAt                 Time        `json:"deleted_at"`
 		InProgressMergeCommitSHA  string      `json:"in_progress_merge_commit_sha"`
 		LockVersion               int         `json:"lock_version"`
 		ApprovalsBeforeMerge      string      `json:"approvals_before_merge"`
 		RebaseCommitSHA           string      `json:"rebase_commit_sha"`
 		TimeEstimate              int         `json:"time_estimate"`
-		LastEditedAt              string      `json:"last_edited_at"`
+		LastEditedAt              Time        `json:"last_edited_at"`
 		LastEditedByID            int         `json:"last_edited_by_id"`
 		Source                    Repository  `json:"source"`
 		Target                    Repository  `json:"target"`
 		LastCommit                struct {
-			ID        string    `json:"id"`
-			Message   string    `json:"message"`
-			Timestamp time.Time `json:"timestamp"`
-			URL       string    `json:"url"`
+			ID        string `json:"id"`
+			Message   string `json:"message"`
+			Timestamp Time   `json:"timestamp"`
+			URL       string `json:"url"`
 			Author    struct {
 				Name  string `json:"name"`
 				Email string `json:"email"`
@@ -175,10 +183,10 @@ type EventPush struct {
 	Project      Project    `json:"project"`
 	Repository   Repository `json:"repository"`
 	Commits      []struct {
-		ID        string    `json:"id"`
-		Message   string    `json:"message"`
-		Timestamp time.Time `json:"timestamp"`
-		URL       string    `json:"url"`
+		ID        string `json:"id"`
+		Message   string `json:"message"`
+		Timestamp Time   `json:"timestamp"`
+		URL       string `json:"url"`
 		Author    struct {
 			Name  string `json:"name"`
 			Email string `json:"email"`
@@ -208,10 +216,10 @@ type EventTagPush struct {
 	Project     Project    `json:"project"`
 	Repository  Repository `json:"repository"`
 	Commits     []struct {
-		ID        string    `json:"id"`
-		Message   string    `json:"message"`
-		Timestamp time.Time `json:"timestamp"`
-		URL       string    `json:"url"`
+		ID        string `json:"id"`
+		Message   string `json:"message"`
+		Timestamp Time   `json:"timestamp"`
+		URL       string `json:"url"`
 		Author    struct {
 			Name  string `json:"name"`
 			Email string `json:"email"`
@@ -250,16 +258,16 @@ type EventIssue struct {
 			Current  int `json:"current"`
 		} `json:"author_id"`
 		CreatedAt struct {
-			Previous string `json:"previous"`
-			Current  string `json:"current"`
+			Previous Time `json:"previous"`
+			Current  Time `json:"current"`
 		} `json:"created_at"`
 		Description struct {
 			Previous string `json:"previous"`
 			Current  string `json:"current"`
 		} `json:"description"`
 		DueDate struct {
-			Previous string `json:"previous"`
-			Current  string `json:"current"`
+			Previous Date `json:"previous"`
+			Current  Date `json:"current"`
 		} `json:"due_date"`
 		ID struct {
 			Previous int `json:"previous"`
@@ -286,8 +294,8 @@ type EventIssue struct {
 			Current  string `json:"current"`
 		} `json:"title"`
 		UpdatedAt struct {
-			Previous string `json:"previous"`
-			Current  string `json:"current"`
+			Previous Time `json:"previous"`
+			Current  Time `json:"current"`
 		} `json:"updated_at"`
 		Weight struct {
 			Previous int `json:"previous"`
@@ -319,8 +327,8 @@ type Issue struct {
 	AssigneeID          int           `json:"assignee_id"`
 	AuthorID            int           `json:"author_id"`
 	ProjectID           int           `json:"project_id"`
-	CreatedAt           string        `json:"created_at"` // Should be time.Time (see Gitlab issue #21468)
-	UpdatedAt           string        `json:"updated_at"` // Should be time.Time (see Gitlab issue #21468)
+	CreatedAt           Time          `json:"created_at"`
+	UpdatedAt           Time          `json:"updated_at"`
 	Position            int           `json:"position"`
 	BranchName          string        `json:"branch_name"`
 	Description         string        `json:"description"`
@@ -329,10 +337,10 @@ type Issue struct {
 	IID                 int           `json:"iid"`
 	URL                 string        `json:"url"`
 	Action              IssueAction   `json:"action"`
-	ClosedAt            interface{}   `json:"closed_at"`
+	ClosedAt            Time          `json:"closed_at"`
 	Confidential        bool          `json:"confidential"`
-	DueDate             interface{}   `json:"due_date"`
-	LastEditedAt        interface{}   `json:"last_edited_at"`
+	DueDate             Date          `json:"due_date"`
+	LastEditedAt        Time          `json:"last_edited_at"`
 	LastEditedByID      interface{}   `json:"last_edited_by_id"`
 	MovedToID           interface{}   `json:"moved_to_id"`
 	DuplicatedToID      interface{}   `json:"duplicated_to_id"`
@@ -381,8 +389,8 @@ type EventJob struct {
 	BuildName         string  `json:"build_name"`
 	BuildStage        string  `json:"build_stage"`
 	BuildStatus       string  `json:"build_status"`
-	BuildStartedAt    string  `json:"build_started_at"`
-	BuildFinishedAt   string  `json:"build_finished_at"`
+	BuildStartedAt    Time    `json:"build_started_at"`
+	BuildFinishedAt   Time    `json:"build_finished_at"`
 	BuildDuration     float64 `json:"build_duration"`
 	BuildAllowFailure bool    `json:"build_allow_failure"`
 	Tag               bool    `json:"tag"`
@@ -403,8 +411,8 @@ type EventJob struct {
 		AuthorURL   string `json:"author_url"`
 		Status      string `json:"status"`
 		Duration    int    `json:"duration"`
-		StartedAt   string `json:"started_at"`
-		FinishedAt  string `json:"finished_at"`
+		StartedAt   Time   `json:"started_at"`
+		FinishedAt  Time   `json:"finished_at"`
 	} `json:"commit"`
 	Repository Repository `json:"repository"`
 }
@@ -424,8 +432,8 @@ type EventCommitComment struct {
 		Note         string `json:"note"`
 		NoteableType string `json:"noteable_type"`
 		AuthorID     int    `json:"author_id"`
-		CreatedAt    string `json:"created_at"`
-		UpdatedAt    string `json:"updated_at"`
+		CreatedAt    Time   `json:"created_at"`
+		UpdatedAt    Time   `json:"updated_at"`
 		ProjectID    int    `json:"project_id"`
 		Attachment   string `json:"attachment"`
 		LineCode     string `json:"line_code"`
@@ -444,10 +452,10 @@ type EventCommitComment struct {
 		} `json:"st_diff"`
 	} `json:"object_attributes"`
 	Commit struct {
-		ID        string    `json:"id"`
-		Message   string    `json:"message"`
-		Timestamp time.Time `json:"timestamp"`
-		URL       string    `json:"url"`
+		ID        string `json:"id"`
+		Message   string `json:"message"`
+		Timestamp Time   `json:"timestamp"`
+		URL       string `json:"url"`
 		Author    struct {
 			Name  string `json:"name"`
 			Email string `json:"email"`
@@ -470,8 +478,8 @@ type EventMergeComment struct {
 		Note         string `json:"note"`
 		NoteableType string `json:"noteable_type"`
 		AuthorID     int    `json:"author_id"`
-		CreatedAt    string `json:"created_at"`
-		UpdatedAt    string `json:"updated_at"`
+		CreatedAt    Time   `json:"created_at"`
+		UpdatedAt    Time   `json:"updated_at"`
 		ProjectID    int    `json:"project_id"`
 		Attachment   string `json:"attachment"`
 		LineCode     string `json:"line_code"`
@@ -490,8 +498,8 @@ type EventMergeComment struct {
 		AuthorID                  int         `json:"author_id"`
 		AssigneeID                int         `json:"assignee_id"`
 		Title                     string      `json:"title"`
-		CreatedAt                 string      `json:"created_at"`
-		UpdatedAt                 string      `json:"updated_at"`
+		CreatedAt                 Time        `json:"created_at"`
+		UpdatedAt                 Time        `json:"updated_at"`
 		MilestoneID               int         `json:"milestone_id"`
 		State                     string      `json:"state"`
 		MergeStatus               string      `json:"merge_status"`
@@ -499,7 +507,7 @@ type EventMergeComment struct {
 		IID                       int         `json:"iid"`
 		Description               string      `json:"description"`
 		Position                  int         `json:"position"`
-		LockedAt                  string      `json:"locked_at"`
+		LockedAt                  Time        `json:"locked_at"`
 		UpdatedByID               int         `json:"updated_by_id"`
 		MergeError                string      `json:"merge_error"`
 		MergeParams               MergeParams `json:"merge_params"`
@@ -508,21 +516,21 @@ type EventMergeComment struct {
 		WorkInProgress            bool        `json:"work_in_progress"`
 		MergeUserID               int         `json:"merge_user_id"`
 		MergeCommitSHA            string      `json:"merge_commit_sha"`
-		DeletedAt                 string      `json:"deleted_at"`
+		DeletedAt                 Time        `json:"deleted_at"`
 		InProgressMergeCommitSHA  string      `json:"in_progress_merge_commit_sha"`
 		LockVersion               int         `json:"lock_version"`
 		ApprovalsBeforeMerge      string      `json:"approvals_before_merge"`
 		RebaseCommitSHA           string      `json:"rebase_commit_sha"`
 		TimeEstimate              int         `json:"time_estimate"`
-		LastEditedAt              string      `json:"last_edited_at"`
+		LastEditedAt              Time        `json:"last_edited_at"`
 		LastEditedByID            int         `json:"last_edited_by_id"`
 		Source                    Repository  `json:"source"`
 		Target                    Repository  `json:"target"`
 		LastCommit                struct {
-			ID        string    `json:"id"`
-			Message   string    `json:"message"`
-			Timestamp time.Time `json:"timestamp"`
-			URL       string    `json:"url"`
+			ID        string `json:"id"`
+			Message   string `json:"message"`
+			Timestamp Time   `json:"timestamp"`
+			URL       string `json:"url"`
 			Author    struct {
 				Name  string `json:"name"`
 				Email string `json:"email"`
@@ -548,8 +556,8 @@ type EventIssueComment struct {
 		Note         string `json:"note"`
 		NoteableType string `json:"noteable_type"`
 		AuthorID     int    `json:"author_id"`
-		CreatedAt    string `json:"created_at"`
-		UpdatedAt    string `json:"updated_at"`
+		CreatedAt    Time   `json:"created_at"`
+		UpdatedAt    Time   `json:"updated_at"`
 		ProjectID    int    `json:"project_id"`
 		Attachment   string `json:"attachment"`
 		LineCode     string `json:"line_code"`
@@ -560,22 +568,21 @@ type EventIssueComment struct {
 		URL          string `json:"url"`
 	} `json:"object_attributes"`
 	Issue struct {
-		ID             int    `json:"id"`
-		IID            int    `json:"iid"`
-		ProjectID      int    `json:"project_id"`
-		MilestoneID    int    `json:"milestone_id"`
-		AuthorID       int    `json:"author_id"`
-		Description    string `json:"description"`
-		State          string `json:"state"`
-		Title          string `json:"title"`
-		LastEditedAt   string `json:"last_edit_at"`
-		LastEditedByID int    `json:"last_edited_by_id"`
-		UpdatedAt      string `json:"updated_at"`
-		UpdatedByID    int    `json:"updated_by_id"`
-		CreatedAt      string `json:"created_at"`
-		ClosedAt       string `json:"closed_at"`
-		// NOTE: check this
-		// DueDate             ISOTime `json:"due_date"`
+		ID                  int    `json:"id"`
+		IID                 int    `json:"iid"`
+		ProjectID           int    `json:"project_id"`
+		MilestoneID         int    `json:"milestone_id"`
+		AuthorID            int    `json:"author_id"`
+		Description         string `json:"description"`
+		State               string `json:"state"`
+		Title               string `json:"title"`
+		LastEditedAt        Time   `json:"last_edit_at"`
+		LastEditedByID      int    `json:"last_edited_by_id"`
+		UpdatedAt           Time   `json:"updated_at"`
+		UpdatedByID         int    `json:"updated_by_id"`
+		CreatedAt           Time   `json:"created_at"`
+		ClosedAt            Time   `json:"closed_at"`
+		DueDate             Date   `json:"due_date"`
 		URL                 string `json:"url"`
 		TimeEstimate        int    `json:"time_estimate"`
 		Confidential        bool   `json:"confidential"`
@@ -602,8 +609,8 @@ type EventSnippetComment struct {
 		Note         string `json:"note"`
 		NoteableType string `json:"noteable_type"`
 		AuthorID     int    `json:"author_id"`
-		CreatedAt    string `json:"created_at"`
-		UpdatedAt    string `json:"updated_at"`
+		CreatedAt    Time   `json:"created_at"`
+		UpdatedAt    Time   `json:"updated_at"`
 		ProjectID    int    `json:"project_id"`
 		Attachment   string `json:"attachment"`
 		LineCode     string `json:"line_code"`
@@ -633,8 +640,8 @@ type EventMergeRequest struct {
 		AssigneeID      int    `json:"assignee_id"`
 		AssigneeIDs     []int  `json:"assignee_ids"`
 		Title           string `json:"title"`
-		CreatedAt       string `json:"created_at"` // Should be time.Time (see Gitlab issue #21468)
-		UpdatedAt       string `json:"updated_at"` // Should be time.Time (see Gitlab issue #21468)
+		CreatedAt       Time   `json:"created_at"`
+		UpdatedAt       Time   `json:"updated_at"`
 		// NOTE: check this:
 		// StCommits                []Commit    `json:"st_commits"`
 		StDiffs                  []Diff      `json:"st_diffs"`
@@ -645,7 +652,7 @@ type EventMergeRequest struct {
 		IID                      int         `json:"iid"`
 		Description              string      `json:"description"`
 		Position                 int         `json:"position"`
-		LockedAt                 string      `json:"locked_at"`
+		LockedAt                 Time        `json:"locked_at"`
 		UpdatedByID              int         `json:"updated_by_id"`
 		MergeError               string      `json:"merge_error"`
 		MergeUserID              int         `json:"merge_user_id"`
@@ -653,7 +660,7 @@ type EventMergeRequest struct {
 		MergeParams              MergeParams `json:"merge_params"`
 		MergeWhenBuildSucceeds   bool        `json:"merge_when_build_succeeds"`
 		WorkInProgress           bool        `json:"work_in_progress"`
-		DeletedAt                string      `json:"deleted_at"`
+		DeletedAt                Time        `json:"deleted_at"`
 		ApprovalsBeforeMerge     string      `json:"approvals_before_merge"`
 		RebaseCommitSHA          string      `json:"rebase_commit_sha"`
 		InProgressMergeCommitSHA string      `json:"in_progress_merge_commit_sha"`
@@ -662,10 +669,10 @@ type EventMergeRequest struct {
 		Source                   Repository  `json:"source"`
 		Target                   Repository  `json:"target"`
 		LastCommit               struct {
-			ID        string    `json:"id"`
-			Message   string    `json:"message"`
-			Timestamp time.Time `json:"timestamp"`
-			URL       string    `json:"url"`
+			ID        string `json:"id"`
+			Message   string `json:"message"`
+			Timestamp Time   `json:"timestamp"`
+			URL       string `json:"url"`
 			Author    struct {
 				Name  string `json:"name"`
 				Email string `json:"email"`
@@ -676,9 +683,11 @@ type EventMergeRequest struct {
 		OldRev   string        `json:"oldrev"`
 		Assignee MergeAssignee `json:"assignee"`
 	} `json:"object_attributes"`
-	Repository Repository    `json:"repository"`
-	Assignee   MergeAssignee `json:"assignee"`
-	Labels     []Label       `json:"labels"`
+	Repository Repository      `json:"repository"`
+	Assignee   MergeAssignee   `json:"assignee"`
+	Assignees  []MergeAssignee `json:"assignees"`
+	Reviewers  []MergeAssignee `json:"reviewers"`
+	Labels     []Label         `json:"labels"`
 	Changes    struct {
 		Assignees struct {
 			Previous []MergeAssignee `json:"previous"`
@@ -806,8 +815,8 @@ type EventPipeline struct {
 		BeforeSHA  string   `json:"before_sha"`
 		Status     string   `json:"status"`
 		Stages     []string `json:"stages"`
-		CreatedAt  string   `json:"created_at"`
-		FinishedAt string   `json:"finished_at"`
+		CreatedAt  Time     `json:"created_at"`
+		FinishedAt Time     `json:"finished_at"`
 		Duration   int      `json:"duration"`
 	} `json:"object_attributes"`
 	MergeRequest struct {
@@ -825,10 +834,10 @@ type EventPipeline struct {
 	User    User    `json:"user"`
 	Project Project `json:"project"`
 	Commit  struct {
-		ID        string    `json:"id"`
-		Message   string    `json:"message"`
-		Timestamp time.Time `json:"timestamp"`
-		URL       string    `json:"url"`
+		ID        string `json:"id"`
+		Message   string `json:"message"`
+		Timestamp Time   `json:"timestamp"`
+		URL       string `json:"url"`
 		Author    struct {
 			Name  string `json:"name"`
 			Email string `json:"email"`
@@ -839,9 +848,9 @@ type EventPipeline struct {
 		Stage      string `json:"stage"`
 		Name       string `json:"name"`
 		Status     string `json:"status"`
-		CreatedAt  string `json:"created_at"`
-		StartedAt  string `json:"started_at"`
-		FinishedAt string `json:"finished_at"`
+		CreatedAt  Time   `json:"created_at"`
+		StartedAt  Time   `json:"started_at"`
+		FinishedAt Time   `json:"finished_at"`
 		When       string `json:"when"`
 		Manual     bool   `json:"manual"`
 		User       User   `json:"user"`
@@ -858,7 +867,7 @@ type EventPipeline struct {
 	} `json:"builds"`
 }
 
-//EventBuild represents a build event
+// EventBuild represents a build event
 //
 // GitLab API docs:
 // https://docs.gitlab.com/ce/user/project/integrations/webhooks.html#build-events
@@ -873,8 +882,8 @@ type EventBuild struct {
 	BuildName         string  `json:"build_name"`
 	BuildStage        string  `json:"build_stage"`
 	BuildStatus       string  `json:"build_status"`
-	BuildStartedAt    string  `json:"build_started_at"`
-	BuildFinishedAt   string  `json:"build_finished_at"`
+	BuildStartedAt    Time    `json:"build_started_at"`
+	BuildFinishedAt   Time    `json:"build_finished_at"`
 	BuildDuration     float64 `json:"build_duration"`
 	ProjectID         int     `json:"project_id"`
 	ProjectName       string  `json:"project_name"`
@@ -891,14 +900,274 @@ type EventBuild struct {
 		AuthorEmail string `json:"author_email"`
 		Status      string `json:"status"`
 		Duration    int    `json:"duration"`
-		StartedAt   string `json:"started_at"`
-		FinishedAt  string `json:"finished_at"`
+		StartedAt   Time   `json:"started_at"`
+		FinishedAt  Time   `json:"finished_at"`
 	} `json:"commit"`
 	Repository Repository `json:"repository"`
 }
 
+// EventDeployment represents a deployment event.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ce/user/project/integrations/webhooks.html#deployment-events
+type EventDeployment struct {
+	ObjectKind             string  `json:"object_kind"`
+	Status                 string  `json:"status"`
+	StatusChangedAt        Time    `json:"status_changed_at"`
+	DeploymentID           int     `json:"deployment_id"`
+	DeployableID           int     `json:"deployable_id"`
+	DeployableURL          string  `json:"deployable_url"`
+	Environment            string  `json:"environment"`
+	EnvironmentSlug        string  `json:"environment_slug"`
+	EnvironmentExternalURL string  `json:"environment_external_url"`
+	Project                Project `json:"project"`
+	ShortSHA               string  `json:"short_sha"`
+	User                   User    `json:"user"`
+	UserURL                string  `json:"user_url"`
+	CommitURL              string  `json:"commit_url"`
+	CommitTitle            string  `json:"commit_title"`
+}
+
+// EventRelease represents a release event.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ce/user/project/integrations/webhooks.html#release-events
+type EventRelease struct {
+	ObjectKind  string  `json:"object_kind"`
+	ID          int     `json:"id"`
+	CreatedAt   Time    `json:"created_at"`
+	Description string  `json:"description"`
+	Name        string  `json:"name"`
+	ReleasedAt  Time    `json:"released_at"`
+	Tag         string  `json:"tag"`
+	Project     Project `json:"project"`
+	URL         string  `json:"url"`
+	Action      string  `json:"action"`
+	Assets      struct {
+		Count int `json:"count"`
+		Links []struct {
+			ID       int    `json:"id"`
+			External bool   `json:"external"`
+			LinkType string `json:"link_type"`
+			Name     string `json:"name"`
+			URL      string `json:"url"`
+		} `json:"links"`
+		Sources []struct {
+			Format string `json:"format"`
+			URL    string `json:"url"`
+		} `json:"sources"`
+	} `json:"assets"`
+	Commit struct {
+		ID        string `json:"id"`
+		Message   string `json:"message"`
+		Title     string `json:"title"`
+		Timestamp Time   `json:"timestamp"`
+		URL       string `json:"url"`
+		Author    struct {
+			Name  string `json:"name"`
+			Email string `json:"email"`
+		} `json:"author"`
+	} `json:"commit"`
+	Milestones []Milestone `json:"milestones"`
+}
+
+// ReleaseAction const
+const (
+	ReleaseActionCreate = "create"
+	ReleaseActionUpdate = "update"
+	ReleaseActionDelete = "delete"
+)
+
+// Milestone represents a GitLab milestone.
+//
+// GitLab API docs: https://docs.gitlab.com/ce/api/milestones.html
+type Milestone struct {
+	ID          int    `json:"id"`
+	IID         int    `json:"iid"`
+	ProjectID   int    `json:"project_id"`
+	GroupID     int    `json:"group_id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	State       string `json:"state"`
+	DueDate     Date   `json:"due_date"`
+	StartDate   Date   `json:"start_date"`
+	WebURL      string `json:"web_url"`
+}
+
+// EventMember represents a group member event.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ee/user/project/integrations/webhook_events.html#group-member-events
+type EventMember struct {
+	EventName    SystemHookEventName `json:"event_name"`
+	CreatedAt    Time                `json:"created_at"`
+	UpdatedAt    Time                `json:"updated_at"`
+	GroupName    string              `json:"group_name"`
+	GroupPath    string              `json:"group_path"`
+	GroupID      int                 `json:"group_id"`
+	UserUsername string              `json:"user_username"`
+	UserName     string              `json:"user_name"`
+	UserEmail    string              `json:"user_email"`
+	UserID       int                 `json:"user_id"`
+	GroupAccess  string              `json:"group_access"`
+	GroupPlan    string              `json:"group_plan"`
+	ExpiresAt    Time                `json:"expires_at"`
+}
+
+// EventSubgroup represents a subgroup event.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ee/user/project/integrations/webhook_events.html#subgroup-events
+type EventSubgroup struct {
+	EventName      SystemHookEventName `json:"event_name"`
+	CreatedAt      Time                `json:"created_at"`
+	UpdatedAt      Time                `json:"updated_at"`
+	Name           string              `json:"name"`
+	Path           string              `json:"path"`
+	FullPath       string              `json:"full_path"`
+	GroupID        int                 `json:"group_id"`
+	ParentGroupID  int                 `json:"parent_group_id"`
+	ParentName     string              `json:"parent_name"`
+	ParentPath     string              `json:"parent_path"`
+	ParentFullPath string              `json:"parent_full_path"`
+}
+
+// EventProject represents a group project event.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ee/user/project/integrations/webhook_events.html#project-events
+type EventProject struct {
+	EventName          SystemHookEventName `json:"event_name"`
+	CreatedAt          Time                `json:"created_at"`
+	UpdatedAt          Time                `json:"updated_at"`
+	Name               string              `json:"name"`
+	Path               string              `json:"path"`
+	PathWithNamespace  string              `json:"path_with_namespace"`
+	ProjectID          int                 `json:"project_id"`
+	ProjectNamespaceID int                 `json:"project_namespace_id"`
+	Owners             []User              `json:"owners"`
+	ProjectVisibility  string              `json:"project_visibility"`
+}
+
+// EventEmoji represents an emoji event.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ee/user/project/integrations/webhook_events.html#emoji-events
+type EventEmoji struct {
+	ObjectKind       string     `json:"object_kind"`
+	EventType        string     `json:"event_type"`
+	User             User       `json:"user"`
+	ProjectID        int        `json:"project_id"`
+	Project          Project    `json:"project"`
+	Repository       Repository `json:"repository"`
+	ObjectAttributes struct {
+		ID            int    `json:"id"`
+		UserID        int    `json:"user_id"`
+		Name          string `json:"name"`
+		AwardableType string `json:"awardable_type"`
+		AwardableID   int    `json:"awardable_id"`
+		CreatedAt     Time   `json:"created_at"`
+		UpdatedAt     Time   `json:"updated_at"`
+	} `json:"object_attributes"`
+	Issue        Issue        `json:"issue"`
+	MergeRequest MergeRequest `json:"merge_request"`
+	Note         Note         `json:"note"`
+	Snippet      struct {
+		ID              int    `json:"id"`
+		Title           string `json:"title"`
+		Content         string `json:"content"`
+		AuthorID        int    `json:"author_id"`
+		ProjectID       int    `json:"project_id"`
+		CreatedAt       Time   `json:"created_at"`
+		UpdatedAt       Time   `json:"updated_at"`
+		FileName        string `json:"file_name"`
+		Type            string `json:"type"`
+		VisibilityLevel int    `json:"visibility_level"`
+		Description     string `json:"description"`
+		URL             string `json:"url"`
+	} `json:"snippet"`
+	Commit struct {
+		ID        string `json:"id"`
+		Message   string `json:"message"`
+		Title     string `json:"title"`
+		Timestamp Time   `json:"timestamp"`
+		URL       string `json:"url"`
+		Author    struct {
+			Name  string `json:"name"`
+			Email string `json:"email"`
+		} `json:"author"`
+	} `json:"commit"`
+}
+
+// EmojiEventType const
+const (
+	EmojiEventTypeAward  = "award"
+	EmojiEventTypeRevoke = "revoke"
+)
+
+// AwardableType const
+const (
+	AwardableTypeIssue        = "Issue"
+	AwardableTypeMergeRequest = "MergeRequest"
+	AwardableTypeNote         = "Note"
+	AwardableTypeSnippet      = "Snippet"
+	AwardableTypeCommit       = "Commit"
+)
+
+// EventFeatureFlag represents a feature flag event.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ee/user/project/integrations/webhook_events.html#feature-flag-events
+type EventFeatureFlag struct {
+	ObjectKind       string  `json:"object_kind"`
+	Project          Project `json:"project"`
+	User             User    `json:"user"`
+	UserURL          string  `json:"user_url"`
+	ObjectAttributes struct {
+		ID          int    `json:"id"`
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Active      bool   `json:"active"`
+	} `json:"object_attributes"`
+}
+
+// MergeRequest represents a merge request.
+type MergeRequest struct {
+	ID              int    `json:"id"`
+	IID             int    `json:"iid"`
+	Title           string `json:"title"`
+	Description     string `json:"description"`
+	State           string `json:"state"`
+	SourceBranch    string `json:"source_branch"`
+	SourceProjectID int    `json:"source_project_id"`
+	TargetBranch    string `json:"target_branch"`
+	TargetProjectID int    `json:"target_project_id"`
+	AuthorID        int    `json:"author_id"`
+	AssigneeIDs     []int  `json:"assignee_ids"`
+	MergeStatus     string `json:"merge_status"`
+	URL             string `json:"url"`
+	CreatedAt       Time   `json:"created_at"`
+	UpdatedAt       Time   `json:"updated_at"`
+}
+
+// Note represents a comment.
+type Note struct {
+	ID           int    `json:"id"`
+	Note         string `json:"note"`
+	NoteableType string `json:"noteable_type"`
+	NoteableID   int    `json:"noteable_id"`
+	AuthorID     int    `json:"author_id"`
+	ProjectID    int    `json:"project_id"`
+	CommitID     string `json:"commit_id"`
+	System       bool   `json:"system"`
+	URL          string `json:"url"`
+	CreatedAt    Time   `json:"created_at"`
+	UpdatedAt    Time   `json:"updated_at"`
+}
+
 // User represents a user
 type User struct {
+	ID        int    `json:"id"`
 	Name      string `json:"name"`
 	Username  string `json:"username"`
 	AvatarURL string `json:"avatar_url"`
@@ -1020,15 +1289,15 @@ type Snippet struct {
 	FileName    string `json:"file_name"`
 	Description string `json:"description"`
 	Author      struct {
-		ID        int       `json:"id"`
-		Username  string    `json:"username"`
-		Email     string    `json:"email"`
-		Name      string    `json:"name"`
-		State     string    `json:"state"`
-		CreatedAt time.Time `json:"created_at"`
+		ID        int    `json:"id"`
+		Username  string `json:"username"`
+		Email     string `json:"email"`
+		Name      string `json:"name"`
+		State     string `json:"state"`
+		CreatedAt Time   `json:"created_at"`
 	} `json:"author"`
-	UpdatedAt time.Time `json:"updated_at"`
-	CreatedAt time.Time `json:"created_at"`
-	WebURL    string    `json:"web_url"`
-	RawURL    string    `json:"raw_url"`
+	UpdatedAt Time   `json:"updated_at"`
+	CreatedAt Time   `json:"created_at"`
+	WebURL    string `json:"web_url"`
+	RawURL    string `json:"raw_url"`
 }
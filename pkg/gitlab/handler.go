@@ -9,12 +9,24 @@ import (
 // FuncBuild function for handler
 type FuncBuild func(context.Context, EventBuild)
 
+// FuncDeployment function for handler
+type FuncDeployment func(context.Context, EventDeployment)
+
+// FuncEmoji function for handler
+type FuncEmoji func(context.Context, EventEmoji)
+
+// FuncFeatureFlag function for handler
+type FuncFeatureFlag func(context.Context, EventFeatureFlag)
+
 // FuncIssue function for handler
 type FuncIssue func(context.Context, EventIssue)
 
 // FuncJob function for handler
 type FuncJob func(context.Context, EventJob)
 
+// FuncMember function for handler
+type FuncMember func(context.Context, EventMember)
+
 // FuncMergeRequest function for handler
 type FuncMergeRequest func(context.Context, EventMergeRequest)
 
@@ -24,11 +36,38 @@ type FuncNote func(context.Context, EventNote)
 // FuncPipeline function for handler
 type FuncPipeline func(context.Context, EventPipeline)
 
+// FuncProject function for handler
+type FuncProject func(context.Context, EventProject)
+
 // FuncPush function for handler
 type FuncPush func(context.Context, EventPush)
 
+// FuncSubgroup function for handler
+type FuncSubgroup func(context.Context, EventSubgroup)
+
 // FuncSystemHook function for handler
-// type FuncSystemHook func(context.Context, EventSystemHook)
+type FuncSystemHook func(context.Context, EventSystemHook)
+
+// FuncSystemHookProject function for handler
+type FuncSystemHookProject func(context.Context, EventSystemHookProject)
+
+// FuncSystemHookTeamMember function for handler
+type FuncSystemHookTeamMember func(context.Context, EventSystemHookTeamMember)
+
+// FuncSystemHookUser function for handler
+type FuncSystemHookUser func(context.Context, EventSystemHookUser)
+
+// FuncSystemHookGroup function for handler
+type FuncSystemHookGroup func(context.Context, EventSystemHookGroup)
+
+// FuncSystemHookGroupMember function for handler
+type FuncSystemHookGroupMember func(context.Context, EventSystemHookGroupMember)
+
+// FuncSystemHookRepositoryUpdate function for handler
+type FuncSystemHookRepositoryUpdate func(context.Context, EventSystemHookRepositoryUpdate)
+
+// FuncRelease function for handler
+type FuncRelease func(context.Context, EventRelease)
 
 // FuncTagPush function for handler
 type FuncTagPush func(context.Context, EventTagPush)
@@ -41,17 +80,35 @@ type FuncUnknown func(context.Context, interface{})
 
 // FuncList struct
 type FuncList struct {
-	build        []FuncBuild
-	issue        []FuncIssue
-	job          []FuncJob
-	mergeRequest []FuncMergeRequest
-	note         []FuncNote
-	pipeline     []FuncPipeline
-	push         []FuncPush
-	tagPush      []FuncTagPush
-	wikiPage     []FuncWikiPage
-	unknown      []FuncUnknown
-	// systemHook   []FuncSystemHook
+	build             []FuncBuild
+	confidentialIssue []FuncIssue
+	confidentialNote  []FuncNote
+	deployment        []FuncDeployment
+	emoji             []FuncEmoji
+	featureFlag       []FuncFeatureFlag
+	issue             []FuncIssue
+	job               []FuncJob
+	member            []FuncMember
+	mergeRequest      []FuncMergeRequest
+	note              []FuncNote
+	pipeline          []FuncPipeline
+	project           []FuncProject
+	push              []FuncPush
+	release           []FuncRelease
+	subgroup          []FuncSubgroup
+	tagPush           []FuncTagPush
+	wikiPage          []FuncWikiPage
+	unknown           []FuncUnknown
+
+	systemHook                 []FuncSystemHook
+	systemHookProject          []FuncSystemHookProject
+	systemHookTeamMember       []FuncSystemHookTeamMember
+	systemHookUser             []FuncSystemHookUser
+	systemHookGroup            []FuncSystemHookGroup
+	systemHookGroupMember      []FuncSystemHookGroupMember
+	systemHookRepositoryUpdate []FuncSystemHookRepositoryUpdate
+
+	middlewares []Middleware
 }
 
 // NewFuncList return FuncList
@@ -60,104 +117,266 @@ func NewFuncList() *FuncList {
 }
 
 // Handler gitlab events
-func (fl FuncList) Handler(ctx context.Context, event EventType, data []byte) error { // nolint:gocyclo
+//
+// Handler decode event and pass it through middleware chain to registered
+// event handlers.
+func (fl FuncList) Handler(ctx context.Context, event EventType, data []byte) error {
+	value, err := decode(event, data)
+	if err != nil {
+		return err
+	}
+
+	h := fl.dispatch
+	for i := len(fl.middlewares) - 1; i >= 0; i-- {
+		h = fl.middlewares[i](h)
+	}
+
+	return h(ctx, Event{
+		Type:  event,
+		Data:  data,
+		Value: value,
+	})
+}
+
+// decode return pointer to decoded event
+func decode(event EventType, data []byte) (interface{}, error) { // nolint:gocyclo
+	var obj interface{}
+
 	switch event {
 	case EventTypeBuild:
-		var obj EventBuild
-		if err := json.Unmarshal(data, &obj); err != nil {
-			return err
+		obj = &EventBuild{}
+	case EventTypeDeployment:
+		obj = &EventDeployment{}
+	case EventTypeEmoji:
+		obj = &EventEmoji{}
+	case EventTypeFeatureFlag:
+		obj = &EventFeatureFlag{}
+	case EventTypeIssue, EventTypeConfidentialIssue:
+		obj = &EventIssue{}
+	case EventTypeJob:
+		obj = &EventJob{}
+	case EventTypeMember:
+		obj = &EventMember{}
+	case EventTypeMergeRequest:
+		obj = &EventMergeRequest{}
+	case EventTypeNote, EventConfidentialTypeNote:
+		obj = &EventNote{}
+	case EventTypePipeline:
+		obj = &EventPipeline{}
+	case EventTypeProject:
+		obj = &EventProject{}
+	case EventTypePush:
+		obj = &EventPush{}
+	case EventTypeRelease:
+		obj = &EventRelease{}
+	case EventTypeSubgroup:
+		obj = &EventSubgroup{}
+	case EventTypeSystemHook:
+		return decodeSystemHook(data)
+	case EventTypeTagPush:
+		obj = &EventTagPush{}
+	case EventTypeWikiPage:
+		obj = &EventWikiPage{}
+	default:
+		var raw interface{}
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return nil, err
 		}
 
-		for _, f := range fl.build {
-			f(ctx, obj)
+		return raw, nil
+	}
+
+	if err := json.Unmarshal(data, obj); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// decodeSystemHook return pointer to decoded system hook keyed by event_name
+func decodeSystemHook(data []byte) (interface{}, error) {
+	var base EventSystemHook
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, err
+	}
+
+	var obj interface{}
+
+	switch base.EventName {
+	case SystemHookProjectCreate, SystemHookProjectDestroy, SystemHookProjectRename,
+		SystemHookProjectTransfer, SystemHookProjectUpdate:
+		obj = &EventSystemHookProject{}
+	case SystemHookUserAddToTeam, SystemHookUserRemoveFromTeam, SystemHookUserUpdateForTeam:
+		obj = &EventSystemHookTeamMember{}
+	case SystemHookUserCreate, SystemHookUserDestroy, SystemHookUserFailedLogin, SystemHookUserRename:
+		obj = &EventSystemHookUser{}
+	case SystemHookGroupCreate, SystemHookGroupDestroy, SystemHookGroupRename:
+		obj = &EventSystemHookGroup{}
+	case SystemHookUserAddToGroup, SystemHookUserRemoveFromGroup, SystemHookUserUpdateForGroup:
+		obj = &EventSystemHookGroupMember{}
+	case SystemHookRepositoryUpdate:
+		obj = &EventSystemHookRepositoryUpdate{}
+	case SystemHookPush:
+		obj = &EventPush{}
+	case SystemHookTagPush:
+		obj = &EventTagPush{}
+	default:
+		if base.ObjectKind != objectKindMergeRequest {
+			return &base, nil
 		}
-	case EventTypeIssue:
-		var obj EventIssue
-		if err := json.Unmarshal(data, &obj); err != nil {
-			return err
+
+		obj = &EventMergeRequest{}
+	}
+
+	if err := json.Unmarshal(data, obj); err != nil {
+		return nil, err
+	}
+
+	return obj, nil
+}
+
+// systemHookBase return common fields of decoded system hook
+func systemHookBase(value interface{}) EventSystemHook {
+	switch obj := value.(type) {
+	case *EventSystemHook:
+		return *obj
+	case *EventSystemHookProject:
+		return obj.EventSystemHook
+	case *EventSystemHookTeamMember:
+		return obj.EventSystemHook
+	case *EventSystemHookUser:
+		return obj.EventSystemHook
+	case *EventSystemHookGroup:
+		return obj.EventSystemHook
+	case *EventSystemHookGroupMember:
+		return obj.EventSystemHook
+	case *EventSystemHookRepositoryUpdate:
+		return obj.EventSystemHook
+	case *EventPush:
+		return EventSystemHook{EventName: SystemHookPush, ObjectKind: obj.ObjectKind}
+	case *EventTagPush:
+		return EventSystemHook{EventName: SystemHookTagPush, ObjectKind: obj.ObjectKind}
+	case *EventMergeRequest:
+		return EventSystemHook{ObjectKind: obj.ObjectKind}
+	default:
+		return EventSystemHook{}
+	}
+}
+
+// dispatch call registered event handlers for decoded event
+func (fl FuncList) dispatch(ctx context.Context, e Event) error { // nolint:gocyclo,funlen
+	if e.Type == EventTypeSystemHook && len(fl.systemHook) > 0 {
+		base := systemHookBase(e.Value)
+		for _, f := range fl.systemHook {
+			f(ctx, base)
 		}
+	}
 
-		for _, f := range fl.issue {
-			f(ctx, obj)
+	switch obj := e.Value.(type) {
+	case *EventBuild:
+		for _, f := range fl.build {
+			f(ctx, *obj)
 		}
-	case EventTypeJob:
-		var obj EventJob
-		if err := json.Unmarshal(data, &obj); err != nil {
-			return err
+	case *EventDeployment:
+		for _, f := range fl.deployment {
+			f(ctx, *obj)
+		}
+	case *EventEmoji:
+		for _, f := range fl.emoji {
+			f(ctx, *obj)
+		}
+	case *EventFeatureFlag:
+		for _, f := range fl.featureFlag {
+			f(ctx, *obj)
+		}
+	case *EventIssue:
+		list := fl.issue
+		if e.Type == EventTypeConfidentialIssue {
+			list = fl.confidentialIssue
 		}
 
+		for _, f := range list {
+			f(ctx, *obj)
+		}
+	case *EventJob:
 		for _, f := range fl.job {
-			f(ctx, obj)
+			f(ctx, *obj)
 		}
-	case EventTypeMergeRequest:
-		var obj EventMergeRequest
-		if err := json.Unmarshal(data, &obj); err != nil {
-			return err
+	case *EventMember:
+		for _, f := range fl.member {
+			f(ctx, *obj)
 		}
-
+	case *EventMergeRequest:
 		for _, f := range fl.mergeRequest {
-			f(ctx, obj)
+			f(ctx, *obj)
 		}
-	case EventTypeNote:
-		var obj EventNote
-		if err := json.Unmarshal(data, &obj); err != nil {
-			return err
+	case *EventNote:
+		list := fl.note
+		if e.Type == EventConfidentialTypeNote {
+			list = fl.confidentialNote
 		}
 
-		for _, f := range fl.note {
-			f(ctx, obj)
-		}
-	case EventTypePipeline:
-		var obj EventPipeline
-		if err := json.Unmarshal(data, &obj); err != nil {
-			return err
+		for _, f := range list {
+			f(ctx, *obj)
 		}
-
+	case *EventPipeline:
 		for _, f := range fl.pipeline {
-			f(ctx, obj)
+			f(ctx, *obj)
 		}
-	case EventTypePush:
-		var obj EventPush
-		if err := json.Unmarshal(data, &obj); err != nil {
-			return err
+	case *EventProject:
+		for _, f := range fl.project {
+			f(ctx, *obj)
 		}
-
+	case *EventPush:
 		for _, f := range fl.push {
-			f(ctx, obj)
+			f(ctx, *obj)
 		}
-	// case EventTypeSystemHook:
-	// 	var obj EventSystemHook
-	// 	if err := json.Unmarshal(data, &obj); err != nil {
-	// 		return err
-	// 	}
-
-	// 	for _, f := range fl.systemHook {
-	// 		f(ctx, obj)
-	// 	}
-	case EventTypeTagPush:
-		var obj EventTagPush
-		if err := json.Unmarshal(data, &obj); err != nil {
-			return err
+	case *EventRelease:
+		for _, f := range fl.release {
+			f(ctx, *obj)
 		}
-
-		for _, f := range fl.tagPush {
+	case *EventSubgroup:
+		for _, f := range fl.subgroup {
+			f(ctx, *obj)
+		}
+	case *EventSystemHook:
+		// system hook with unknown event_name
+		for _, f := range fl.unknown {
 			f(ctx, obj)
 		}
-	case EventTypeWikiPage:
-		var obj EventWikiPage
-		if err := json.Unmarshal(data, &obj); err != nil {
-			return err
+	case *EventSystemHookProject:
+		for _, f := range fl.systemHookProject {
+			f(ctx, *obj)
 		}
-
+	case *EventSystemHookTeamMember:
+		for _, f := range fl.systemHookTeamMember {
+			f(ctx, *obj)
+		}
+	case *EventSystemHookUser:
+		for _, f := range fl.systemHookUser {
+			f(ctx, *obj)
+		}
+	case *EventSystemHookGroup:
+		for _, f := range fl.systemHookGroup {
+			f(ctx, *obj)
+		}
+	case *EventSystemHookGroupMember:
+		for _, f := range fl.systemHookGroupMember {
+			f(ctx, *obj)
+		}
+	case *EventSystemHookRepositoryUpdate:
+		for _, f := range fl.systemHookRepositoryUpdate {
+			f(ctx, *obj)
+		}
+	case *EventTagPush:
+		for _, f := range fl.tagPush {
+			f(ctx, *obj)
+		}
+	case *EventWikiPage:
 		for _, f := range fl.wikiPage {
-			f(ctx, obj)
+			f(ctx, *obj)
 		}
 	default:
-		var obj interface{}
-		if err := json.Unmarshal(data, &obj); err != nil {
-			return err
-		}
-
 		for _, f := range fl.unknown {
 			f(ctx, obj)
 		}
@@ -166,11 +385,42 @@ func (fl FuncList) Handler(ctx context.Context, event EventType, data []byte) er
 	return nil
 }
 
+// Use add middlewares to the chain. Middlewares are called in the order
+// they were added, before registered event handlers.
+func (fl *FuncList) Use(mw ...Middleware) {
+	fl.middlewares = append(fl.middlewares, mw...)
+}
+
 // OnBuild event handler
 func (fl *FuncList) OnBuild(f FuncBuild) {
 	fl.build = append(fl.build, f)
 }
 
+// OnConfidentialIssue event handler
+func (fl *FuncList) OnConfidentialIssue(f FuncIssue) {
+	fl.confidentialIssue = append(fl.confidentialIssue, f)
+}
+
+// OnConfidentialNote event handler
+func (fl *FuncList) OnConfidentialNote(f FuncNote) {
+	fl.confidentialNote = append(fl.confidentialNote, f)
+}
+
+// OnDeployment event handler
+func (fl *FuncList) OnDeployment(f FuncDeployment) {
+	fl.deployment = append(fl.deployment, f)
+}
+
+// OnEmoji event handler
+func (fl *FuncList) OnEmoji(f FuncEmoji) {
+	fl.emoji = append(fl.emoji, f)
+}
+
+// OnFeatureFlag event handler
+func (fl *FuncList) OnFeatureFlag(f FuncFeatureFlag) {
+	fl.featureFlag = append(fl.featureFlag, f)
+}
+
 // OnIssue event handler
 func (fl *FuncList) OnIssue(f FuncIssue) {
 	fl.issue = append(fl.issue, f)
@@ -181,6 +431,11 @@ func (fl *FuncList) OnJob(f FuncJob) {
 	fl.job = append(fl.job, f)
 }
 
+// OnMember event handler
+func (fl *FuncList) OnMember(f FuncMember) {
+	fl.member = append(fl.member, f)
+}
+
 // OnMergeRequest event handler
 func (fl *FuncList) OnMergeRequest(f FuncMergeRequest) {
 	fl.mergeRequest = append(fl.mergeRequest, f)
@@ -196,15 +451,60 @@ func (fl *FuncList) OnPipeline(f FuncPipeline) {
 	fl.pipeline = append(fl.pipeline, f)
 }
 
+// OnProject event handler
+func (fl *FuncList) OnProject(f FuncProject) {
+	fl.project = append(fl.project, f)
+}
+
 // OnPush event handler
 func (fl *FuncList) OnPush(f FuncPush) {
 	fl.push = append(fl.push, f)
 }
 
-// OnSystemHook event handler
-// func (fl *FuncList) OnSystemHook(f FuncSystemHook) {
-// 	fl.systemHook = append(fl.systemHook, f)
-// }
+// OnRelease event handler
+func (fl *FuncList) OnRelease(f FuncRelease) {
+	fl.release = append(fl.release, f)
+}
+
+// OnSubgroup event handler
+func (fl *FuncList) OnSubgroup(f FuncSubgroup) {
+	fl.subgroup = append(fl.subgroup, f)
+}
+
+// OnSystemHook event handler, called for every system hook event
+func (fl *FuncList) OnSystemHook(f FuncSystemHook) {
+	fl.systemHook = append(fl.systemHook, f)
+}
+
+// OnSystemHookProject event handler
+func (fl *FuncList) OnSystemHookProject(f FuncSystemHookProject) {
+	fl.systemHookProject = append(fl.systemHookProject, f)
+}
+
+// OnSystemHookTeamMember event handler
+func (fl *FuncList) OnSystemHookTeamMember(f FuncSystemHookTeamMember) {
+	fl.systemHookTeamMember = append(fl.systemHookTeamMember, f)
+}
+
+// OnSystemHookUser event handler
+func (fl *FuncList) OnSystemHookUser(f FuncSystemHookUser) {
+	fl.systemHookUser = append(fl.systemHookUser, f)
+}
+
+// OnSystemHookGroup event handler
+func (fl *FuncList) OnSystemHookGroup(f FuncSystemHookGroup) {
+	fl.systemHookGroup = append(fl.systemHookGroup, f)
+}
+
+// OnSystemHookGroupMember event handler
+func (fl *FuncList) OnSystemHookGroupMember(f FuncSystemHookGroupMember) {
+	fl.systemHookGroupMember = append(fl.systemHookGroupMember, f)
+}
+
+// OnSystemHookRepositoryUpdate event handler
+func (fl *FuncList) OnSystemHookRepositoryUpdate(f FuncSystemHookRepositoryUpdate) {
+	fl.systemHookRepositoryUpdate = append(fl.systemHookRepositoryUpdate, f)
+}
 
 // OnTagPush event handler
 func (fl *FuncList) OnTagPush(f FuncTagPush) {
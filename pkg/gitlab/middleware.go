@@ -0,0 +1,53 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+)
+
+// Event represents a received gitlab event.
+type Event struct {
+	// Type of event from X-Gitlab-Event header
+	Type EventType
+
+	// Data is raw event body
+	Data []byte
+
+	// Value is pointer to decoded event, for example *EventPush.
+	// Unknown events are decoded to interface{}.
+	Value interface{}
+}
+
+// HandlerFunc handle decoded gitlab event
+type HandlerFunc func(context.Context, Event) error
+
+// Middleware wrap HandlerFunc
+type Middleware func(next HandlerFunc) HandlerFunc
+
+// PanicError is returned by Recoverer when event handler panics.
+type PanicError struct {
+	Type  EventType
+	Value interface{}
+}
+
+// Error implements the error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("gitlab: panic in %s handler: %v", e.Type, e.Value)
+}
+
+// Recoverer middleware recover panic in next handlers and return it as
+// *PanicError.
+func Recoverer(next HandlerFunc) HandlerFunc {
+	return func(ctx context.Context, e Event) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = &PanicError{
+					Type:  e.Type,
+					Value: r,
+				}
+			}
+		}()
+
+		return next(ctx, e)
+	}
+}
@@ -0,0 +1,140 @@
+package gitlab
+
+// SystemHookEventName represents a system hook event name.
+type SystemHookEventName string
+
+// List of available system hook event names.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ee/administration/system_hooks.html
+const (
+	SystemHookProjectCreate       SystemHookEventName = "project_create"
+	SystemHookProjectDestroy      SystemHookEventName = "project_destroy"
+	SystemHookProjectRename       SystemHookEventName = "project_rename"
+	SystemHookProjectTransfer     SystemHookEventName = "project_transfer"
+	SystemHookProjectUpdate       SystemHookEventName = "project_update"
+	SystemHookUserAddToTeam       SystemHookEventName = "user_add_to_team"
+	SystemHookUserRemoveFromTeam  SystemHookEventName = "user_remove_from_team"
+	SystemHookUserUpdateForTeam   SystemHookEventName = "user_update_for_team"
+	SystemHookUserCreate          SystemHookEventName = "user_create"
+	SystemHookUserDestroy         SystemHookEventName = "user_destroy"
+	SystemHookUserFailedLogin     SystemHookEventName = "user_failed_login"
+	SystemHookUserRename          SystemHookEventName = "user_rename"
+	SystemHookGroupCreate         SystemHookEventName = "group_create"
+	SystemHookGroupDestroy        SystemHookEventName = "group_destroy"
+	SystemHookGroupRename         SystemHookEventName = "group_rename"
+	SystemHookUserAddToGroup      SystemHookEventName = "user_add_to_group"
+	SystemHookUserRemoveFromGroup SystemHookEventName = "user_remove_from_group"
+	SystemHookUserUpdateForGroup  SystemHookEventName = "user_update_for_group"
+	SystemHookUserAccessRequest   SystemHookEventName = "user_access_request_to_group"
+	SystemHookUserAccessDenied    SystemHookEventName = "user_access_request_denied_for_group"
+	SystemHookSubgroupCreate      SystemHookEventName = "subgroup_create"
+	SystemHookSubgroupDestroy     SystemHookEventName = "subgroup_destroy"
+	SystemHookRepositoryUpdate    SystemHookEventName = "repository_update"
+	SystemHookPush                SystemHookEventName = "push"
+	SystemHookTagPush             SystemHookEventName = "tag_push"
+)
+
+// objectKindMergeRequest is object_kind of merge request system hook, which
+// has no event_name.
+const objectKindMergeRequest = "merge_request"
+
+// EventSystemHook represents common fields of all system hook events.
+//
+// GitLab API docs:
+// https://docs.gitlab.com/ee/administration/system_hooks.html
+type EventSystemHook struct {
+	EventName  SystemHookEventName `json:"event_name"`
+	ObjectKind string              `json:"object_kind"`
+	CreatedAt  Time                `json:"created_at"`
+	UpdatedAt  Time                `json:"updated_at"`
+}
+
+// EventSystemHookProject represents a project_create, project_destroy,
+// project_rename, project_transfer and project_update system hook events.
+type EventSystemHookProject struct {
+	EventSystemHook
+	Name                 string `json:"name"`
+	OwnerEmail           string `json:"owner_email"`
+	OwnerName            string `json:"owner_name"`
+	Owners               []User `json:"owners"`
+	Path                 string `json:"path"`
+	PathWithNamespace    string `json:"path_with_namespace"`
+	OldPathWithNamespace string `json:"old_path_with_namespace"`
+	ProjectID            int    `json:"project_id"`
+	ProjectVisibility    string `json:"project_visibility"`
+}
+
+// EventSystemHookTeamMember represents a user_add_to_team,
+// user_remove_from_team and user_update_for_team system hook events.
+type EventSystemHookTeamMember struct {
+	EventSystemHook
+	AccessLevel       string `json:"access_level"`
+	PathWithNamespace string `json:"path_with_namespace"`
+	ProjectID         int    `json:"project_id"`
+	ProjectName       string `json:"project_name"`
+	ProjectPath       string `json:"project_path"`
+	ProjectVisibility string `json:"project_visibility"`
+	UserEmail         string `json:"user_email"`
+	UserName          string `json:"user_name"`
+	UserUsername      string `json:"user_username"`
+	UserID            int    `json:"user_id"`
+}
+
+// EventSystemHookUser represents a user_create, user_destroy,
+// user_failed_login and user_rename system hook events.
+type EventSystemHookUser struct {
+	EventSystemHook
+	Email       string `json:"email"`
+	Name        string `json:"name"`
+	Username    string `json:"username"`
+	OldUsername string `json:"old_username"`
+	UserID      int    `json:"user_id"`
+	State       string `json:"state"`
+}
+
+// EventSystemHookGroup represents a group_create, group_destroy and
+// group_rename system hook events.
+type EventSystemHookGroup struct {
+	EventSystemHook
+	Name        string `json:"name"`
+	OwnerEmail  string `json:"owner_email"`
+	OwnerName   string `json:"owner_name"`
+	Path        string `json:"path"`
+	OldPath     string `json:"old_path"`
+	FullPath    string `json:"full_path"`
+	OldFullPath string `json:"old_full_path"`
+	GroupID     int    `json:"group_id"`
+}
+
+// EventSystemHookGroupMember represents a user_add_to_group,
+// user_remove_from_group and user_update_for_group system hook events.
+type EventSystemHookGroupMember struct {
+	EventSystemHook
+	GroupAccess  string `json:"group_access"`
+	GroupID      int    `json:"group_id"`
+	GroupName    string `json:"group_name"`
+	GroupPath    string `json:"group_path"`
+	UserEmail    string `json:"user_email"`
+	UserName     string `json:"user_name"`
+	UserUsername string `json:"user_username"`
+	UserID       int    `json:"user_id"`
+}
+
+// EventSystemHookRepositoryUpdate represents a repository_update system
+// hook event.
+type EventSystemHookRepositoryUpdate struct {
+	EventSystemHook
+	UserID     int     `json:"user_id"`
+	UserName   string  `json:"user_name"`
+	UserEmail  string  `json:"user_email"`
+	UserAvatar string  `json:"user_avatar"`
+	ProjectID  int     `json:"project_id"`
+	Project    Project `json:"project"`
+	Changes    []struct {
+		Before string `json:"before"`
+		After  string `json:"after"`
+		Ref    string `json:"ref"`
+	} `json:"changes"`
+	Refs []string `json:"refs"`
+}
@@ -0,0 +1,130 @@
+package gitlab
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Time layouts, which GitLab sends in different versions and events.
+const (
+	layoutUTC      = "2006-01-02 15:04:05 UTC"
+	layoutZone     = "2006-01-02 15:04:05 -0700"
+	layoutNoZone   = "2006-01-02 15:04:05"
+	layoutISONoTZ  = "2006-01-02T15:04:05"
+	layoutDateOnly = "2006-01-02"
+)
+
+// parseTime parse GitLab time in any known format
+func parseTime(s string) (time.Time, error) {
+	for _, layout := range []string{
+		time.RFC3339Nano,
+		layoutUTC,
+		layoutZone,
+		layoutNoZone,
+		layoutISONoTZ,
+		layoutDateOnly,
+	} {
+		t, err := time.Parse(layout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("gitlab: unknown time format %q", s)
+}
+
+// unquoteTime return time string from JSON value. Empty string is returned
+// for null.
+func unquoteTime(data []byte) (string, error) {
+	if bytes.Equal(data, []byte("null")) {
+		return "", nil
+	}
+
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return "", err
+	}
+
+	return s, nil
+}
+
+// Time represents a GitLab timestamp.
+//
+// It accepts RFC3339, "2006-01-02 15:04:05 UTC", "2006-01-02 15:04:05 -0700",
+// date-only formats and null. Value in unknown format is decoded as zero
+// time, so one odd field does not drop whole event.
+type Time struct {
+	time.Time
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (t *Time) UnmarshalJSON(data []byte) error {
+	t.Time = time.Time{}
+
+	s, err := unquoteTime(data)
+	if err != nil || s == "" {
+		return nil
+	}
+
+	if parsed, err := parseTime(s); err == nil {
+		t.Time = parsed
+	}
+
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (t Time) MarshalJSON() ([]byte, error) {
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(t.Format(time.RFC3339))
+}
+
+// Date represents a GitLab date without time, like due_date.
+//
+// It accepts the same formats as Time and null. Value in unknown format is
+// decoded as zero date.
+type Date struct {
+	time.Time
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.
+func (d *Date) UnmarshalJSON(data []byte) error {
+	d.Time = time.Time{}
+
+	s, err := unquoteTime(data)
+	if err != nil || s == "" {
+		return nil
+	}
+
+	t, err := parseTime(s)
+	if err != nil {
+		return nil
+	}
+
+	d.Time = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.
+func (d Date) MarshalJSON() ([]byte, error) {
+	if d.IsZero() {
+		return []byte("null"), nil
+	}
+
+	return json.Marshal(d.Format(layoutDateOnly))
+}
+
+// String return date in "2006-01-02" format.
+func (d Date) String() string {
+	if d.IsZero() {
+		return ""
+	}
+
+	return d.Format(layoutDateOnly)
+}
@@ -0,0 +1,129 @@
+package gitlab
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestTime_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want time.Time
+	}{
+		{
+			name: "RFC3339",
+			data: `"2021-03-04T05:06:07Z"`,
+			want: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+		},
+		{
+			name: "RFC3339 with offset",
+			data: `"2021-03-04T08:06:07.123+03:00"`,
+			want: time.Date(2021, 3, 4, 5, 6, 7, 123e6, time.UTC),
+		},
+		{
+			name: "UTC",
+			data: `"2021-03-04 05:06:07 UTC"`,
+			want: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+		},
+		{
+			name: "offset",
+			data: `"2021-03-04 08:06:07 +0300"`,
+			want: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+		},
+		{
+			name: "negative offset",
+			data: `"2021-03-04 00:06:07 -0500"`,
+			want: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+		},
+		{
+			name: "date only",
+			data: `"2021-03-04"`,
+			want: time.Date(2021, 3, 4, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "null",
+			data: `null`,
+		},
+		{
+			name: "empty",
+			data: `""`,
+		},
+		{
+			name: "unknown format",
+			data: `"yesterday"`,
+		},
+		{
+			name: "number",
+			data: `1614834367`,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			var v struct {
+				CreatedAt Time `json:"created_at"`
+			}
+
+			err := json.Unmarshal([]byte(`{"created_at":`+tt.data+`}`), &v)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+
+			if !v.CreatedAt.Equal(tt.want) {
+				t.Errorf("Unmarshal() = %v, want %v", v.CreatedAt.Time, tt.want)
+			}
+		})
+	}
+}
+
+func TestDate_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{name: "date only", data: `"2021-03-04"`, want: "2021-03-04"},
+		{name: "RFC3339", data: `"2021-03-04T23:06:07Z"`, want: "2021-03-04"},
+		{name: "UTC", data: `"2021-03-04 05:06:07 UTC"`, want: "2021-03-04"},
+		{name: "null", data: `null`, want: ""},
+		{name: "empty", data: `""`, want: ""},
+		{name: "unknown format", data: `"soon"`, want: ""},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			var d Date
+			if err := json.Unmarshal([]byte(tt.data), &d); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+
+			if got := d.String(); got != tt.want {
+				t.Errorf("Unmarshal() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTime_MarshalJSON(t *testing.T) {
+	for _, v := range []Time{{}, {time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)}} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatalf("Marshal() error = %v", err)
+		}
+
+		var got Time
+		if err := json.Unmarshal(data, &got); err != nil {
+			t.Fatalf("Unmarshal() error = %v", err)
+		}
+
+		if !got.Equal(v.Time) {
+			t.Errorf("round trip of %v = %v", v.Time, got.Time)
+		}
+	}
+}
@@ -0,0 +1,277 @@
+package gitlab
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io/ioutil"
+	"net/http"
+)
+
+// DefaultMaxBodySize is default limit of webhook body size.
+const DefaultMaxBodySize = 5 << 20 // 5 Mbyte
+
+// Webhook errors
+var (
+	ErrMethodNotAllowed = errors.New("gitlab: method not allowed")
+	ErrMissingEvent     = errors.New("gitlab: missing " + HeaderEvent + " header")
+	ErrInvalidToken     = errors.New("gitlab: invalid " + HeaderToken + " header")
+	ErrBodyTooLarge     = errors.New("gitlab: request body too large")
+)
+
+// HTTPError is error of event handler with HTTP status code, which is
+// returned to GitLab.
+type HTTPError struct {
+	Code int
+	Err  error
+}
+
+// Error implements the error interface.
+func (e *HTTPError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap return wrapped error.
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+// TokenVerifier verify X-Gitlab-Token of request.
+type TokenVerifier interface {
+	VerifyToken(r *http.Request, token string) bool
+}
+
+// TokenVerifierFunc is an adapter to allow the use of ordinary functions
+// as TokenVerifier.
+type TokenVerifierFunc func(r *http.Request, token string) bool
+
+// VerifyToken calls f(r, token).
+func (f TokenVerifierFunc) VerifyToken(r *http.Request, token string) bool {
+	return f(r, token)
+}
+
+// StaticToken return TokenVerifier, which compares token with secret in
+// constant time.
+func StaticToken(secret string) TokenVerifier {
+	return TokenVerifierFunc(func(_ *http.Request, token string) bool {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(secret)) == 1
+	})
+}
+
+// Deduplicator remember delivered webhooks.
+type Deduplicator interface {
+	// Seen return true if delivery with key was already seen, otherwise
+	// remember it.
+	Seen(key string) bool
+
+	// Forget delivery with key, so it can be processed again.
+	Forget(key string)
+}
+
+// DeliveryKey return unique key of webhook delivery. It uses
+// X-Gitlab-Webhook-UUID and X-Gitlab-Event-UUID headers or payload hash
+// when they are missing. Request path is a part of the key, so the same
+// event delivered to different URLs has different keys.
+func DeliveryKey(r *http.Request, data []byte) string {
+	webhookUUID := r.Header.Get(HeaderWebhookUUID)
+	eventUUID := r.Header.Get(HeaderEventUUID)
+
+	if eventUUID != "" {
+		return r.URL.Path + ":" + webhookUUID + ":" + eventUUID
+	}
+
+	h := sha256.New()
+	_, _ = h.Write([]byte(r.Header.Get(HeaderEvent)))
+	_, _ = h.Write(data)
+
+	return r.URL.Path + ":sha256:" + hex.EncodeToString(h.Sum(nil))
+}
+
+// WebhookRequest represents metadata of webhook request.
+type WebhookRequest struct {
+	Event       EventType
+	Token       string
+	EventUUID   string
+	WebhookUUID string
+	DeliveryKey string
+	RemoteAddr  string
+	Header      http.Header
+}
+
+type contextKey int
+
+const contextWebhookRequest contextKey = iota
+
+// RequestFromContext return webhook request metadata.
+func RequestFromContext(ctx context.Context) (WebhookRequest, bool) {
+	if ctx != nil {
+		if r, ok := ctx.Value(contextWebhookRequest).(WebhookRequest); ok {
+			return r, true
+		}
+	}
+
+	return WebhookRequest{}, false
+}
+
+// WebhookOption configures WebhookHandler.
+type WebhookOption func(*WebhookHandler)
+
+// WithTokenVerifier set token verifier. Without verifier all tokens are
+// accepted.
+func WithTokenVerifier(v TokenVerifier) WebhookOption {
+	return func(h *WebhookHandler) {
+		h.verifier = v
+	}
+}
+
+// WithMaxBodySize set limit of request body size.
+func WithMaxBodySize(n int64) WebhookOption {
+	return func(h *WebhookHandler) {
+		h.maxBodySize = n
+	}
+}
+
+// WithBaseContext set function, which return base context for request.
+// By default r.Context() is used.
+func WithBaseContext(f func(r *http.Request) context.Context) WebhookOption {
+	return func(h *WebhookHandler) {
+		h.baseContext = f
+	}
+}
+
+// WithErrorLog set function, which is called on every failed request.
+func WithErrorLog(f func(r *http.Request, status int, err error)) WebhookOption {
+	return func(h *WebhookHandler) {
+		h.errorLog = f
+	}
+}
+
+// WithDeduplicator set deduplicator. Already seen deliveries are
+// acknowledged without handling.
+func WithDeduplicator(d Deduplicator) WebhookOption {
+	return func(h *WebhookHandler) {
+		h.dedup = d
+	}
+}
+
+// WebhookHandler is http.Handler for GitLab webhooks.
+type WebhookHandler struct {
+	fl          *FuncList
+	verifier    TokenVerifier
+	dedup       Deduplicator
+	maxBodySize int64
+	baseContext func(r *http.Request) context.Context
+	errorLog    func(r *http.Request, status int, err error)
+}
+
+// NewWebhookHandler return http.Handler, which pass GitLab webhooks to fl.
+func NewWebhookHandler(fl *FuncList, opts ...WebhookOption) *WebhookHandler {
+	h := &WebhookHandler{
+		fl:          fl,
+		maxBodySize: DefaultMaxBodySize,
+	}
+
+	for _, opt := range opts {
+		opt(h)
+	}
+
+	return h
+}
+
+// ServeHTTP implements the http.Handler interface.
+func (h *WebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		h.error(w, r, http.StatusMethodNotAllowed, ErrMethodNotAllowed)
+		return
+	}
+
+	meta := WebhookRequest{
+		Event:       EventType(r.Header.Get(HeaderEvent)),
+		Token:       r.Header.Get(HeaderToken),
+		EventUUID:   r.Header.Get(HeaderEventUUID),
+		WebhookUUID: r.Header.Get(HeaderWebhookUUID),
+		RemoteAddr:  r.RemoteAddr,
+		Header:      r.Header,
+	}
+
+	if meta.Event == "" {
+		h.error(w, r, http.StatusBadRequest, ErrMissingEvent)
+		return
+	}
+
+	if h.verifier != nil && !h.verifier.VerifyToken(r, meta.Token) {
+		h.error(w, r, http.StatusForbidden, ErrInvalidToken)
+		return
+	}
+
+	if h.maxBodySize > 0 && r.ContentLength > h.maxBodySize {
+		h.error(w, r, http.StatusRequestEntityTooLarge, ErrBodyTooLarge)
+		return
+	}
+
+	body := r.Body
+	if h.maxBodySize > 0 {
+		body = http.MaxBytesReader(w, r.Body, h.maxBodySize)
+	}
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		if h.maxBodySize > 0 && int64(len(data)) >= h.maxBodySize {
+			h.error(w, r, http.StatusRequestEntityTooLarge, ErrBodyTooLarge)
+		} else {
+			h.error(w, r, http.StatusBadRequest, err)
+		}
+
+		return
+	}
+
+	meta.DeliveryKey = DeliveryKey(r, data)
+
+	if h.dedup != nil && h.dedup.Seen(meta.DeliveryKey) {
+		_, _ = w.Write([]byte(http.StatusText(http.StatusOK)))
+		return
+	}
+
+	ctx := r.Context()
+	if h.baseContext != nil {
+		ctx = h.baseContext(r)
+	}
+
+	ctx = context.WithValue(ctx, contextWebhookRequest, meta)
+
+	err = h.fl.Handler(ctx, meta.Event, data)
+	if err != nil {
+		if h.dedup != nil {
+			h.dedup.Forget(meta.DeliveryKey)
+		}
+
+		var (
+			httpErr  *HTTPError
+			panicErr *PanicError
+		)
+
+		switch {
+		case errors.As(err, &httpErr):
+			h.error(w, r, httpErr.Code, err)
+		case errors.As(err, &panicErr):
+			h.error(w, r, http.StatusInternalServerError, err)
+		default:
+			h.error(w, r, http.StatusBadRequest, err)
+		}
+
+		return
+	}
+
+	_, _ = w.Write([]byte(http.StatusText(http.StatusOK)))
+}
+
+func (h *WebhookHandler) error(w http.ResponseWriter, r *http.Request, status int, err error) {
+	if h.errorLog != nil {
+		h.errorLog(r, status, err)
+	}
+
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(http.StatusText(status)))
+}